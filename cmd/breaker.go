@@ -0,0 +1,54 @@
+// Copyright © 2018 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bmc-toolbox/dora/connectors"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+)
+
+// resetBreakerCmd represents the reset-breaker command
+var resetBreakerCmd = &cobra.Command{
+	Use:   "reset-breaker",
+	Short: "Reset the circuit breakers of failing bmcs so they are collected again",
+	Long: `Reset the circuit breakers of failing bmcs so they are collected again.
+The bmcs whose collection keeps failing are skipped for a cool down and
+eventually dead lettered, see /api/v1/circuit_breakers.
+
+usage: dora collect reset-breaker 192.168.0.1
+       dora collect reset-breaker all
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			return
+		}
+
+		reset, err := connectors.ResetBreakers(storage.InitDB(), args)
+		if err != nil {
+			fmt.Printf("Failed to reset the circuit breakers: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("reset %d circuit breakers\n", reset)
+	},
+}
+
+func init() {
+	collectCmd.AddCommand(resetBreakerCmd)
+}
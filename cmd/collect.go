@@ -38,7 +38,6 @@ usage: dora collect
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		configItems := []string{
-			"bmc_user",
 			"collector.concurrency",
 			"collector.dump_invalid_payloads",
 			"collector.dump_invalid_payload_path",
@@ -55,9 +54,15 @@ usage: dora collect
 			}
 		}
 
+		// the bmc_user credential is optional with a credentials backend
+		bmcUser := viper.IsSet("bmc_user")
 		bmcPass := viper.IsSet("bmc_pass")
 		bmcPassFile := viper.IsSet("bmc_pass_file")
-		if !bmcPass && !bmcPassFile {
+		if !bmcUser && viper.GetString("collector.credentials.backend") == "" {
+			fmt.Printf("Parameter bmc_user is missing in the config file\n")
+			os.Exit(1)
+		}
+		if bmcUser && !bmcPass && !bmcPassFile {
 			fmt.Printf("One of the bmc_pass/bmc_pass_file parameters is missing in the config file\n")
 			os.Exit(1)
 		}
@@ -84,5 +89,5 @@ usage: dora collect
 
 func init() {
 	RootCmd.AddCommand(collectCmd)
-	collectCmd.Flags().BoolVarP(&force, "force", "f", false, "force blade scan")
+	collectCmd.Flags().BoolVarP(&force, "force", "f", false, "force blade scan and ignore the circuit breakers")
 }
@@ -37,6 +37,8 @@ database_max_connections: 10
 
 api:
   http_server_port: 8000
+  # the subnet source and its exclusions are reloaded once older than this, or on SIGHUP
+  source_cache_ttl: 1m
 
 notification:
   enabled: false
@@ -54,6 +56,58 @@ metrics:
 
 collector:
   concurrency: 60
+  # stored as the worker of the collection attempts, defaults to the hostname
+  collected_by: anomalia
+
+  # transient network failures are retried with an exponential backoff,
+  # refused logins and unknown devices aren't
+  retry:
+    attempts: 2
+    backoff: 2s
+    max_backoff: 30s
+
+  # consecutive failed collections of a bmc open its circuit breaker for the
+  # cool down, after dead_letter_after it's no longer collected until reset
+  # with dora collect reset-breaker. A threshold of 0 disables it
+  breaker:
+    threshold: 3
+    cooldown: 1h
+    dead_letter_after: 10
+
+  # every collection is bound by the host deadline and each of its stages by
+  # its own, 0 being no bound. On SIGINT or SIGTERM no new host is taken and
+  # the ones being collected have shutdown_grace to finish
+  timeouts:
+    host: 20m
+    scan: 1m
+    login: 1m
+    detect: 1m
+    collect: 5m
+    enrich: 10m
+    blade: 2m
+  shutdown_grace: 30s
+
+  # the blades of a chassis are enriched by their own bmc, concurrency of
+  # them at a time, each within the blade timeout and all of them within the
+  # enrich one. The chassis is stored whatever happened to its blades
+  blades:
+    concurrency: 4
+
+  # hosts whose discover hint, or http fingerprint before their first
+  # collection, is one of the hints of a pool are collected by its own
+  # workers with its own timeouts, falling back to the ones above. The other
+  # hosts are collected by the default pool of concurrency workers
+  # pools:
+  #   dell:
+  #     hints: [idrac8, idrac9, m1000e]
+  #     concurrency: 20
+  #     timeouts:
+  #       host: 20m
+  #       collect: 15m
+  #   hp:
+  #     hints: [hpilo, hpc7000, hpcl100]
+  #     concurrency: 10
+
 
   worker:
     enabled: false
@@ -61,8 +115,22 @@ collector:
     queue: dora
     username:
     password:
+    # the exclusions checked before collecting are reloaded once older than
+    # this, or on SIGHUP
+    source_cache_ttl: 1m
+
+  # candidate credentials of each bmc, tried in order before bmc_user and
+  # collector.default.<vendor>: "file" reads collector.credentials.file and
+  # "exec" runs collector.credentials.command with the bmc in its environment
+  credentials:
+    backend: ""
+    file: /etc/bmc-toolbox/credentials.yaml
+    command: /usr/local/bin/dora-credentials
+    timeout: 10s
 
-  try_default_credentials: false
+  # try collector.default.<vendor> last (the default), the bmcs accepting them
+  # are reported at /api/v1/security_findings
+  try_default_credentials: true
   default:
     dell:
       username: Priest
@@ -78,10 +146,124 @@ collector:
 
 scanner:
   scanned_by: anomalia
+  # probe tasks, one address with every probe of its profile, run concurrently across all subnets
   concurrency: 100
+  # default probe timeout, used when a profile entry doesn't set one
+  timeout: 1s
+  snmp_community: public
+  # bmc web interfaces are slow, open https ports get their redfish service root
+  # and landing page fetched within this timeout to seed the discover hints
+  http_fingerprint_timeout: 5s
+  # mark the ports that didn't answer the latest scan of their network as stale
+  # and remove the ones of networks gone from the subnet source, see dora prune
+  prune_after_scan: false
+  # scan results are upserted in batches of db_batch_size rows, a failed batch
+  # is retried db_batch_retries times before being counted in scan.db_save_failed
+  db_batch_size: 500
+  db_batch_retries: 3
+  # check each address with an icmp echo, over unprivileged datagram sockets
+  # (net.ipv4.ping_group_range), or a tcp connect to 443 when icmp can't be used
+  # before probing it, addresses that don't answer aren't probed: their ports
+  # keep what was stored or are recorded as not_probed. liveness_tcp_fallback
+  # also tries 443 when the echo got no reply, doubling the timeout of the dead
+  liveness_check: false
+  liveness_timeout: 1s
+  liveness_tcp_fallback: false
+  # probes run for each address, protocols: tcp, ipmi, kvm, http and snmp,
+  # https tcp ports are fingerprinted and their certificate recorded, 443 always is
+  profiles:
+    default:
+      - protocol: tcp
+        port: 22
+        timeout: 1s
+      - protocol: tcp
+        port: 443
+        timeout: 1s
+      - protocol: ipmi
+        port: 623
+        timeout: 1s
+    nonstandard:
+      - protocol: tcp
+        port: 22
+      - protocol: tcp
+        port: 443
+      - protocol: tcp
+        port: 8443
+        retries: 1
+        https: true
+      - protocol: http
+        port: 80
+      - protocol: kvm
+        port: 5900
+      - protocol: ipmi
+        port: 623
+      - protocol: snmp
+        port: 161
+  # politeness of the scanner, by default for each site: probes per second
+  # shared by the subnets of the site, probes open at once towards a /24 and
+  # a daily window of local time without probes, 0 or empty means no limit
+  rate_limit:
+    probes_per_second: 0
+    connections_per_24: 0
+    quiet_hours: ""
+  # addresses never scanned nor collected: addresses, networks, first-last
+  # ranges or offsets within each subnet, +N from the network address and -N
+  # from the broadcast address, e.g. the gateway and a vrrp pair
+  exclude:
+    - "+1"
+    - "-2"
+    - "-3"
+  # parent ranges of the networks that can be scanned ad hoc, with a site,
+  # when they aren't in the subnet source
+  adhoc_allowed_ranges:
+    - 10.0.0.0/8
+  # ports of networks scanned ad hoc are pruned once their last scan is older than this
+  adhoc_retention: 168h
+  # per site settings, a site without profile uses the default one and a site
+  # without rate_limit the one above, exclude adds to the global one
+  sites:
+    ams4:
+      profile: nonstandard
+      exclude:
+        - 10.255.0.10
+      rate_limit:
+        probes_per_second: 50
+        connections_per_24: 4
+        quiet_hours: 08:00-18:00
+  # subnets with a rate limit of their own, their probes aren't counted in the site's
+  subnet_rate_limits:
+    - cidr: 10.255.0.0/24
+      probes_per_second: 5
+      connections_per_24: 1
   kea_config: /etc/kea/kea-dhcp4.conf
+  # ipv6 networks are never enumerated, only reservations, active leases and
+  # the addresses listed in ipv6_hosts_file are scanned
+  # kea_config6: /etc/kea/kea-dhcp6.conf
+  # kea_leases6: /var/lib/kea/kea-leases6.csv
+  # ipv6_hosts_file: /etc/bmc-toolbox/ipv6-hosts
+  # restrict the ipv4 scans to the addresses with an active lease, read from the
+  # lease4 table of the kea lease database when its type (postgres or mysql) is
+  # set or from the dhcp4 memfile, mysql options need parseTime=true
+  kea_leases_only: false
+  # kea_leases4: /var/lib/kea/kea-leases4.csv
+  # kea_lease_database_type: postgres
+  # kea_lease_database_options: host=kea-db user=dora dbname=kea sslmode=disable
+  # one of kea, file, dhcpd or directory
   subnet_source: kea
   kea_domain_name_suffix: bmc.example.com
+  # user-context keys of kea subnets and shared-networks, the site key takes
+  # precedence over the domain-name suffix and the exclude key skips the subnet
+  kea_site_key: site
+  kea_exclude_key: dora-exclude
+  # user-context key listing exclusions of a subnet or shared-network, the
+  # reservations and pools with kea_exclude_key set are excluded as well
+  kea_exclude_hosts_key: dora-exclude-hosts
+  # yaml or csv file mapping networks to sites, used by subnet_source: file
+  subnet_file: /etc/bmc-toolbox/subnets.yaml
+  # used by subnet_source: dhcpd, sites are taken from the domain-name option
+  dhcpd_config: /etc/dhcp/dhcpd.conf
+  # one file per site with a network per line, used by subnet_source: directory
+  subnet_directory: /etc/bmc-toolbox/subnets.d
 `)
 
 // createCmd represents the create command
@@ -16,6 +16,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/spf13/cobra"
@@ -38,8 +40,24 @@ usage: dora scan list
 			args = append(args, "all")
 		}
 
-		for _, subnet := range scanner.ListSubnets(args, viper.GetStringSlice("site")) {
-			fmt.Printf("subnet:%s site:%s\n", subnet.CIDR, subnet.Site)
+		subnets, err := scanner.ListSubnets(args, viper.GetStringSlice("site"))
+		if err != nil {
+			fmt.Printf("Failed to load subnets from %s: %s\n", viper.GetString("scanner.subnet_source"), err)
+			os.Exit(1)
+		}
+
+		for _, subnet := range subnets {
+			exclusions, err := scanner.ExclusionsFor(subnet)
+			if err != nil {
+				fmt.Printf("Invalid exclusions: %s\n", err)
+				os.Exit(1)
+			}
+
+			if excluded := exclusions.Entries(); len(excluded) != 0 {
+				fmt.Printf("subnet:%s site:%s exclude:%s\n", subnet.CIDR, subnet.Site, strings.Join(excluded, ","))
+			} else {
+				fmt.Printf("subnet:%s site:%s\n", subnet.CIDR, subnet.Site)
+			}
 		}
 	},
 }
@@ -0,0 +1,58 @@
+// Copyright © 2018 Juliano Martinez <juliano.martinez@booking.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bmc-toolbox/dora/scanner"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/spf13/cobra"
+)
+
+var dryRun bool
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Prune stale scan results and the ones of networks that are gone",
+	Long: `Prune stale scan results and the ones of networks that are gone.
+Scanned ports not refreshed by the latest scan of their network are
+marked as stale and won't be collected anymore, scanned ports of networks
+no longer returned by the subnet source are removed.
+
+usage: dora prune
+       dora prune --dry-run
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		result, err := scanner.Prune(storage.InitDB(), dryRun)
+		if err != nil {
+			fmt.Printf("Failed to prune the scanned ports: %s\n", err)
+			os.Exit(1)
+		}
+
+		if dryRun {
+			fmt.Printf("would mark %d scanned ports as stale and remove %d\n", result.Stale, result.Removed)
+			return
+		}
+		fmt.Printf("marked %d scanned ports as stale and removed %d\n", result.Stale, result.Removed)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "only count the scanned ports that would be pruned")
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/bmc-toolbox/dora/connectors"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/bmc-toolbox/dora/storage"
@@ -70,7 +71,16 @@ usage: dora publish 192.168.0.0/24 -q dora -s scan
 		switch subject {
 		case "scan":
 			subject = "dora::scan"
-			subnets := scanner.LoadSubnets(viper.GetString("scanner.subnet_source"), args, viper.GetStringSlice("site"))
+			subnets, err := scanner.LoadSubnets(viper.GetString("scanner.subnet_source"), args, viper.GetStringSlice("site"))
+			if err != nil {
+				log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "loading subnets"}).Fatal(err)
+			}
+			run, err := scanner.NewScanRun(storage.InitDB(), scanner.TriggeredByCLI, subnets)
+			if err != nil {
+				log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "creating scan run"}).Warn(err)
+			} else {
+				log.WithFields(log.Fields{"queue": queue, "subject": subject, "scan_run_id": run.ID}).Info("scan run created")
+			}
 			for _, subnet := range subnets {
 				s, err := json.Marshal(subnet)
 				if err != nil {
@@ -95,7 +105,7 @@ usage: dora publish 192.168.0.0/24 -q dora -s scan
 			if args[0] == "all" {
 				db := storage.InitDB()
 				var hosts []model.ScannedPort
-				if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open'").Find(&hosts).Error; err != nil {
+				if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open' and stale = ?", false).Find(&hosts).Error; err != nil {
 					log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "retrieving scanned hosts", "ip": "all"}).Error(err)
 				} else {
 					args = []string{}
@@ -104,7 +114,25 @@ usage: dora publish 192.168.0.0/24 -q dora -s scan
 					}
 				}
 			}
+			exclusions, err := scanner.NewExclusionChecker()
+			if err != nil {
+				log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "loading exclusions"}).Error(err)
+				return
+			}
+			blocked, err := connectors.BlockedHosts(storage.InitDB())
+			if err != nil {
+				log.WithFields(log.Fields{"queue": queue, "subject": subject, "operation": "loading circuit breakers"}).Error(err)
+				return
+			}
 			for _, payload := range args {
+				if exclusions.Excluded(payload) {
+					log.WithFields(log.Fields{"queue": queue, "subject": subject, "payload": payload}).Warn("excluded ip, skipping")
+					continue
+				}
+				if blocked[payload] {
+					log.WithFields(log.Fields{"queue": queue, "subject": subject, "payload": payload}).Warn("circuit breaker open, skipping")
+					continue
+				}
 				nc.Publish(subject, []byte(payload))
 				nc.Flush()
 				graphiteKey := "collect.send_successfully"
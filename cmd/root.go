@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
@@ -95,10 +96,31 @@ func initConfig() {
 	// Collector
 	viper.SetDefault("collector.dump_invalid_payloads", false)
 	viper.SetDefault("collector.dump_invalid_payload_path", "/tmp/dora/dumps")
+	viper.SetDefault("collector.credentials.backend", "")
+	viper.SetDefault("collector.credentials.file", "/etc/bmc-toolbox/credentials.yaml")
+	viper.SetDefault("collector.credentials.timeout", 10*time.Second)
+	viper.SetDefault("collector.try_default_credentials", true)
+	viper.SetDefault("collector.retry.attempts", 2)
+	viper.SetDefault("collector.retry.backoff", 2*time.Second)
+	viper.SetDefault("collector.retry.max_backoff", 30*time.Second)
+	viper.SetDefault("collector.breaker.threshold", 3)
+	viper.SetDefault("collector.breaker.cooldown", time.Hour)
+	viper.SetDefault("collector.breaker.dead_letter_after", 10)
+	viper.SetDefault("collector.timeouts.host", 20*time.Minute)
+	viper.SetDefault("collector.timeouts.scan", time.Minute)
+	viper.SetDefault("collector.timeouts.login", time.Minute)
+	viper.SetDefault("collector.timeouts.detect", time.Minute)
+	viper.SetDefault("collector.timeouts.collect", 5*time.Minute)
+	viper.SetDefault("collector.timeouts.enrich", 10*time.Minute)
+	viper.SetDefault("collector.timeouts.blade", 2*time.Minute)
+	viper.SetDefault("collector.shutdown_grace", 30*time.Second)
+	viper.SetDefault("collector.worker.source_cache_ttl", time.Minute)
+	viper.SetDefault("collector.blades.concurrency", 4)
 
 	// Api
 	viper.SetDefault("api.http_server_port", 8000)
 	viper.SetDefault("api.ro_database", false)
+	viper.SetDefault("api.source_cache_ttl", time.Minute)
 
 	// Notification
 	viper.SetDefault("notification.enabled", false)
@@ -108,8 +130,33 @@ func initConfig() {
 	// Scan
 	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
 	viper.SetDefault("scanner.kea_config", "/etc/kea/kea-dhcp4.conf")
+	viper.SetDefault("scanner.kea_config6", "")
+	viper.SetDefault("scanner.kea_leases6", "")
+	viper.SetDefault("scanner.kea_leases_only", false)
+	viper.SetDefault("scanner.kea_leases4", "")
+	viper.SetDefault("scanner.kea_lease_database_type", "")
+	viper.SetDefault("scanner.kea_lease_database_options", "")
+	viper.SetDefault("scanner.ipv6_hosts_file", "")
+	viper.SetDefault("scanner.kea_site_key", "site")
+	viper.SetDefault("scanner.kea_exclude_key", "dora-exclude")
+	viper.SetDefault("scanner.kea_exclude_hosts_key", "dora-exclude-hosts")
+	viper.SetDefault("scanner.exclude", []string{})
+	viper.SetDefault("scanner.adhoc_allowed_ranges", []string{})
+	viper.SetDefault("scanner.adhoc_retention", 7*24*time.Hour)
 	viper.SetDefault("scanner.subnet_source", "kea")
+	viper.SetDefault("scanner.subnet_file", "/etc/bmc-toolbox/subnets.yaml")
+	viper.SetDefault("scanner.dhcpd_config", "/etc/dhcp/dhcpd.conf")
+	viper.SetDefault("scanner.subnet_directory", "/etc/bmc-toolbox/subnets.d")
 	viper.SetDefault("scanner.concurrency", 100)
+	viper.SetDefault("scanner.timeout", time.Second)
+	viper.SetDefault("scanner.snmp_community", "public")
+	viper.SetDefault("scanner.http_fingerprint_timeout", 5*time.Second)
+	viper.SetDefault("scanner.prune_after_scan", false)
+	viper.SetDefault("scanner.db_batch_size", 500)
+	viper.SetDefault("scanner.db_batch_retries", 3)
+	viper.SetDefault("scanner.liveness_check", false)
+	viper.SetDefault("scanner.liveness_timeout", time.Second)
+	viper.SetDefault("scanner.liveness_tcp_fallback", false)
 
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -117,6 +164,7 @@ func initConfig() {
 	}
 
 	viper.SetDefault("scanner.scanned_by", hostname)
+	viper.SetDefault("collector.collected_by", hostname)
 
 	viper.AutomaticEnv() // read in environment variables that match
 
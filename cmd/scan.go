@@ -24,6 +24,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+var profile string
+var adHocSite string
+
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
 	Use:   "scan",
@@ -32,14 +35,32 @@ var scanCmd = &cobra.Command{
 for the required tcp and udp ports for the hardware discovery. It will build a list of 
 discoverable assets to be later used by dora collector
 
+Networks that aren't in the subnet source are scanned ad hoc as part of the
+given site, when they are within scanner.adhoc_allowed_ranges
+
 usage: dora scan
 	   dora scan 192.168.0.0/24
+	   dora scan --site ams4 10.20.0.0/24
+	   dora scan --profile supermicro 192.168.0.0/24
 	   dora scan list
 	   dora scan loadSubnets <subnetSource>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		// This will avoid a deadlock in metrics. They are not setup at this stage
 		viper.Set("metrics.enabled", false)
+
+		if profile != "" {
+			profiles, err := scanner.LoadProfiles()
+			if err != nil {
+				fmt.Printf("Failed to load the scan profiles: %s\n", err)
+				os.Exit(1)
+			}
+			if _, ok := profiles[profile]; !ok {
+				fmt.Printf("Unknown scan profile: %s\n", profile)
+				os.Exit(1)
+			}
+		}
+
 		if len(args) != 0 && args[0] != "all" {
 			var subnets []string
 			for _, subnet := range args {
@@ -50,13 +71,15 @@ usage: dora scan
 				}
 				subnets = append(subnets, subnet)
 			}
-			scanner.ScanNetworks(subnets, viper.GetStringSlice("site"))
+			scanner.ScanNetworks(subnets, viper.GetStringSlice("site"), adHocSite, profile)
 		} else {
-			scanner.ScanNetworks([]string{"all"}, viper.GetStringSlice("site"))
+			scanner.ScanNetworks([]string{"all"}, viper.GetStringSlice("site"), "", profile)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().StringVarP(&profile, "profile", "p", "", "scan profile to be used instead of the one configured for each site")
+	scanCmd.Flags().StringVarP(&adHocSite, "site", "s", "", "site of the networks that aren't in the subnet source, to scan them ad hoc")
 }
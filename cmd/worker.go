@@ -17,7 +17,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"runtime"
 	"time"
 
 	"github.com/bmc-toolbox/dora/connectors"
@@ -53,9 +52,12 @@ usage: dora worker
 			go metrics.Scheduler(time.Minute, metrics.GoRuntimeStats, []string{})
 			go metrics.Scheduler(time.Minute, metrics.MeasureRuntime, []string{"uptime"}, time.Now())
 		}
-		scanner.ScanNetworksWorker()
+		waitScans := scanner.ScanNetworksWorker()
 		connectors.DataCollectionWorker()
-		runtime.Goexit()
+		waitScans()
+		if viper.GetBool("metrics.enabled") {
+			metrics.Close(viper.GetBool("debug"))
+		}
 	},
 }
 
@@ -0,0 +1,46 @@
+package connectors
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// newCollectionAttempt starts the record of the collection of the ip
+func newCollectionAttempt(ip string, source string) *model.CollectionAttempt {
+	return &model.CollectionAttempt{
+		IP:        ip,
+		Worker:    viper.GetString("collector.collected_by"),
+		Source:    source,
+		Stage:     model.CollectionStageScan,
+		StartedAt: time.Now(),
+	}
+}
+
+// recordCollectionAttempt stores the attempt once it's over. Without a
+// failure it succeeded, a blade managed by its chassis is skipped and
+// anything else failed
+func recordCollectionAttempt(db *gorm.DB, attempt *model.CollectionAttempt, failure string, cause error) {
+	attempt.FinishedAt = time.Now()
+	attempt.Failure = failure
+	switch failure {
+	case "":
+		attempt.Result = model.CollectionSucceeded
+		attempt.Stage = model.CollectionStageDone
+	case model.FailureManagedBlade:
+		attempt.Result = model.CollectionSkipped
+	default:
+		attempt.Result = model.CollectionFailed
+	}
+	if cause != nil {
+		attempt.Error = cause.Error()
+	}
+
+	if err := db.Create(attempt).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "recording collection attempt", "ip": attempt.IP}).Error(err)
+	}
+}
@@ -0,0 +1,80 @@
+package connectors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func testDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would get its own in memory database
+	db.DB().SetMaxOpenConns(1)
+	db.SingularTable(true)
+	db.AutoMigrate(
+		&model.CollectionAttempt{},
+		&model.CircuitBreaker{},
+		&model.DiscoverHint{},
+		&model.HTTPFingerprint{},
+		&model.Chassis{},
+		&model.Blade{},
+		&model.Nic{},
+		&model.Disk{},
+		&model.Psu{},
+		&model.StorageBlade{},
+	)
+	return db
+}
+
+func TestRecordCollectionAttempt(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("collector.collected_by", "worker-1")
+	defer viper.Set("collector.collected_by", nil)
+
+	tt := []struct {
+		ip      string
+		stage   string
+		failure string
+		cause   error
+		result  string
+	}{
+		{"10.0.0.1", model.CollectionStageCollect, "", nil, model.CollectionSucceeded},
+		{"10.0.0.2", model.CollectionStageLogin, model.FailureWrongCredentials, errors.New("failed to login"), model.CollectionFailed},
+		{"10.0.0.3", model.CollectionStageDetect, model.FailureManagedBlade, nil, model.CollectionSkipped},
+	}
+
+	for _, tc := range tt {
+		attempt := newCollectionAttempt(tc.ip, "worker")
+		attempt.Stage = tc.stage
+		recordCollectionAttempt(db, attempt, tc.failure, tc.cause)
+	}
+
+	for _, tc := range tt {
+		var attempt model.CollectionAttempt
+		if err := db.Where("ip = ?", tc.ip).First(&attempt).Error; err != nil {
+			t.Fatalf("%s: %s", tc.ip, err)
+		}
+		if attempt.Result != tc.result || attempt.Failure != tc.failure || attempt.Worker != "worker-1" || attempt.Source != "worker" || attempt.ID == "" {
+			t.Errorf("%s: unexpected attempt %+v", tc.ip, attempt)
+		}
+		if tc.cause != nil && attempt.Error != tc.cause.Error() {
+			t.Errorf("%s: expected the error %q, found %q", tc.ip, tc.cause, attempt.Error)
+		}
+		if tc.failure == "" && attempt.Stage != model.CollectionStageDone {
+			t.Errorf("%s: expected a successful attempt to be done, found %s", tc.ip, attempt.Stage)
+		}
+		if tc.failure != "" && attempt.Stage != tc.stage {
+			t.Errorf("%s: expected the attempt to stop at %s, found %s", tc.ip, tc.stage, attempt.Stage)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclib/discover"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// bladeStage bounds the enrichment of each blade with collector.timeouts.blade
+const bladeStage = "blade"
+
+// bladeEnricher reads what the bmc of a blade tells
+var bladeEnricher = enrichBladeFromBmc
+
+// bladeSlots are the positions of the blades of a chassis by their
+// enrichment status
+type bladeSlots struct {
+	enriched []int
+	skipped  []int
+	failed   []int
+	timedOut []int
+}
+
+// String lists the slots for the logs
+func (s bladeSlots) String() string {
+	return fmt.Sprintf("enriched: [%s] skipped: [%s] failed: [%s] timed out: [%s]", joinSlots(s.enriched), joinSlots(s.skipped), joinSlots(s.failed), joinSlots(s.timedOut))
+}
+
+func joinSlots(slots []int) string {
+	sort.Ints(slots)
+	positions := make([]string, len(slots))
+	for i, slot := range slots {
+		positions[i] = strconv.Itoa(slot)
+	}
+	return strings.Join(positions, ",")
+}
+
+// enrichBlades completes the blades of the chassis with what their own bmc
+// tells, collector.blades.concurrency of them at a time, until ctx is done.
+// A blade that can't be enriched keeps what the chassis told and its error,
+// the ones not done in time are timed out
+func enrichBlades(ctx context.Context, pool *collectionPool, chassis *model.Chassis, db *gorm.DB, credentials CredentialProvider) (slots bladeSlots) {
+	concurrency := viper.GetInt("collector.blades.concurrency")
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+
+	for _, blade := range chassis.Blades {
+		wg.Add(1)
+		go func(blade *model.Blade) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				if ctx.Err() == nil {
+					enrichBlade(ctx, pool, blade, db, credentials)
+				}
+				<-sem
+			case <-ctx.Done():
+			}
+			if err := ctx.Err(); err != nil && blade.EnrichmentStatus == "" {
+				err = fmt.Errorf("%s stage interrupted: %w", bladeStage, err)
+				blade.EnrichmentStatus = interruptedStatus(err)
+				blade.EnrichmentError = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch blade.EnrichmentStatus {
+			case model.BladeEnriched:
+				slots.enriched = append(slots.enriched, blade.BladePosition)
+			case model.BladeSkipped:
+				slots.skipped = append(slots.skipped, blade.BladePosition)
+			case model.BladeTimedOut:
+				slots.timedOut = append(slots.timedOut, blade.BladePosition)
+			default:
+				slots.failed = append(slots.failed, blade.BladePosition)
+			}
+		}(blade)
+	}
+	wg.Wait()
+
+	log.WithFields(log.Fields{"operation": "collection", "ip": chassis.BmcAddress, "serial": chassis.Serial}).Infof("blades %s", slots)
+	return slots
+}
+
+// enrichBlade enriches a copy of the blade within the blade deadline and
+// only keeps it when it's done in time, an abandoned enrichment can't touch
+// the blade being stored
+func enrichBlade(ctx context.Context, pool *collectionPool, blade *model.Blade, db *gorm.DB, credentials CredentialProvider) {
+	if blade.BmcAddress == "" {
+		blade.EnrichmentStatus = model.BladeSkipped
+		blade.EnrichmentError = "no bmc address"
+		return
+	}
+
+	enriched, enrich := *blade, bladeEnricher
+	err := runStage(ctx, pool, bladeStage, func(context.Context) error {
+		return enrich(&enriched, db, credentials)
+	})
+	if !interrupted(err) {
+		*blade = enriched
+	}
+
+	switch {
+	case err == nil:
+		blade.EnrichmentStatus = model.BladeEnriched
+		blade.EnrichmentError = ""
+		log.WithFields(log.Fields{"operation": "collection", "ip": blade.BmcAddress}).Info("success")
+		return
+	case err == ErrNotBladeBmc:
+		blade.EnrichmentStatus = model.BladeSkipped
+	case interrupted(err):
+		blade.EnrichmentStatus = interruptedStatus(err)
+	default:
+		blade.EnrichmentStatus = model.BladeFailed
+	}
+	blade.EnrichmentError = err.Error()
+	log.WithFields(log.Fields{"operation": "collection", "ip": blade.BmcAddress, "serial": blade.Serial, "position": blade.BladePosition}).Error(err)
+}
+
+// interruptedStatus is the status of a blade whose enrichment was
+// interrupted: timed out on a deadline, skipped when the collector shuts down
+func interruptedStatus(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return model.BladeSkipped
+	}
+	return model.BladeTimedOut
+}
+
+// enrichBladeFromBmc logs into the bmc of the blade and reads what the
+// chassis doesn't tell. Failing to read a part is only a warning
+func enrichBladeFromBmc(blade *model.Blade, db *gorm.DB, credentials CredentialProvider) (err error) {
+	hintOpts := hintOptsInit(blade.BmcAddress, db)
+	updateSacMetricFn := scanAndConnectMetricInit()
+
+	query := credentialQuery(blade.BmcAddress, db)
+	credential := connectCredential(credentials, query)
+	conn, err := discover.ScanAndConnect(
+		blade.BmcAddress,
+		credential.Username,
+		credential.Password,
+		hintOpts...,
+	)
+	if err != nil {
+		return err
+	}
+	updateSacMetricFn()
+
+	b, ok := conn.(devices.Bmc)
+	if !ok {
+		if c, ok := conn.(devices.Cmc); ok {
+			c.Close()
+		}
+		return ErrNotBladeBmc
+	}
+	defer b.Close(context.Background())
+
+	bladeCredentialID, bladeAuthMethod, err := login(b, credentials, query)
+	if err != nil {
+		if err == ErrLoginFailed {
+			blade.AuthMethod = bladeAuthMethod
+		}
+		return err
+	}
+
+	blade.BmcAuth = true
+	blade.CredentialID = bladeCredentialID
+	blade.AuthMethod = bladeAuthMethod
+	blade.BmcWEBReachable = true
+
+	var scans []model.ScannedPort
+	db.Where("ip = ?", blade.BmcAddress).Find(&scans)
+	for _, scan := range scans {
+		if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
+			blade.BmcSSHReachable = true
+		} else if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
+			blade.BmcWEBReachable = true
+		} else if scan.Port == 623 && scan.Protocol == "ipmi" && scan.State == "open" {
+			blade.BmcIpmiReachable = true
+		}
+	}
+
+	blade.BmcType = b.HardwareType()
+
+	blade.Processor, blade.ProcessorCount, blade.ProcessorCoreCount, blade.ProcessorThreadCount, err = b.CPU()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading cpu data", "ip": blade.BmcAddress, "name": blade.Name, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	blade.Memory, err = b.Memory()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading memory data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	blade.BmcLicenceType, blade.BmcLicenceStatus, err = b.License()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "reading license data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+	}
+
+	if len(blade.Nics) == 0 {
+		nics, err := b.Nics()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "reading nice", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+		} else {
+			for _, nic := range nics {
+				blade.Nics = append(blade.Nics, &model.Nic{
+					MacAddress:  nic.MacAddress,
+					Name:        nic.Name,
+					BladeSerial: blade.Serial,
+				})
+			}
+		}
+	}
+
+	if len(blade.Disks) == 0 {
+		disks, err := b.Disks()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "reading disks", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
+		} else {
+			for pos, disk := range disks {
+				if disk.Serial == "" {
+					disk.Serial = fmt.Sprintf("%s-failed-%d", blade.Serial, pos)
+				}
+
+				blade.Disks = append(blade.Disks, &model.Disk{
+					Serial:      disk.Serial,
+					Size:        disk.Size,
+					Status:      disk.Status,
+					Model:       disk.Model,
+					Location:    disk.Location,
+					Type:        disk.Type,
+					FwVersion:   disk.FwVersion,
+					BladeSerial: blade.Serial,
+				})
+			}
+		}
+	}
+
+	return nil
+}
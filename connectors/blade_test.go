@@ -0,0 +1,119 @@
+package connectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+)
+
+func TestEnrichBlades(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	credentials, err := NewCredentialProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("collector.blades.concurrency", 2)
+	defer viper.Set("collector.blades.concurrency", nil)
+
+	chassis := &model.Chassis{
+		Serial: "CZ3SX5",
+		Blades: []*model.Blade{
+			{Serial: "blade-1", BladePosition: 1},
+			{Serial: "blade-2", BladePosition: 2},
+			{Serial: "blade-3", BladePosition: 3},
+		},
+	}
+
+	slots := enrichBlades(context.Background(), nil, chassis, db, credentials)
+	if joinSlots(slots.skipped) != "1,2,3" || len(slots.enriched) != 0 || len(slots.failed) != 0 {
+		t.Errorf("expected the blades without bmc address to be skipped, found %s", slots)
+	}
+	if chassis.Blades[0].EnrichmentStatus != model.BladeSkipped || chassis.Blades[0].EnrichmentError != "no bmc address" {
+		t.Errorf("expected a blade without bmc address to be skipped, found %+v", chassis.Blades[0])
+	}
+
+	// the collection of the chassis went past its deadline
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chassis = &model.Chassis{
+		Serial: "CZ3SX6",
+		Blades: []*model.Blade{
+			{Serial: "blade-4", BladePosition: 4, BmcAddress: "192.0.2.1", Name: "from the chassis"},
+		},
+	}
+	slots = enrichBlades(ctx, nil, chassis, db, credentials)
+	if joinSlots(slots.skipped) != "4" {
+		t.Errorf("expected an interrupted chassis not to enrich its blades, found %s", slots)
+	}
+	if blade := chassis.Blades[0]; blade.EnrichmentError == "" || blade.Name != "from the chassis" {
+		t.Errorf("expected the blade to keep what the chassis told with its error, found %+v", blade)
+	}
+}
+
+func TestEnrichAndStoreChassis(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("collector.blades.concurrency", 2)
+	viper.Set("collector.timeouts.enrich", 50*time.Millisecond)
+	defer func() {
+		viper.Set("collector.blades.concurrency", nil)
+		viper.Set("collector.timeouts.enrich", nil)
+	}()
+
+	release := make(chan struct{})
+	defer close(release)
+	bladeEnricher = func(blade *model.Blade, db *gorm.DB, credentials CredentialProvider) error {
+		if blade.Serial == "slow" {
+			<-release
+		}
+		blade.Processor = "Intel(R) Xeon(R)"
+		return nil
+	}
+	defer func() { bladeEnricher = enrichBladeFromBmc }()
+
+	chassis := &model.Chassis{
+		Serial: "CZ3SX5",
+		Blades: []*model.Blade{
+			{Serial: "fast", BladePosition: 1, BmcAddress: "192.0.2.1", ChassisSerial: "CZ3SX5"},
+			{Serial: "slow", BladePosition: 2, BmcAddress: "192.0.2.2", ChassisSerial: "CZ3SX5"},
+		},
+	}
+
+	slots, err := enrichAndStoreChassis(context.Background(), nil, chassis, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joinSlots(slots.enriched) != "1" || joinSlots(slots.timedOut) != "2" {
+		t.Errorf("expected blade 1 enriched and blade 2 timed out, found %s", slots)
+	}
+
+	stored, err := storage.NewChassisStorage(db).GetOne("CZ3SX5")
+	if err != nil {
+		t.Fatalf("expected the chassis to be stored despite the slow blade: %s", err)
+	}
+	for _, blade := range stored.Blades {
+		switch blade.Serial {
+		case "fast":
+			if blade.EnrichmentStatus != model.BladeEnriched || blade.Processor == "" {
+				t.Errorf("expected the fast blade to be stored enriched, found %+v", blade)
+			}
+		case "slow":
+			if blade.EnrichmentStatus != model.BladeTimedOut || blade.EnrichmentError == "" || blade.Processor != "" {
+				t.Errorf("expected the slow blade to be stored timed out as the chassis told, found %+v", blade)
+			}
+		}
+	}
+	if len(stored.Blades) != 2 {
+		t.Errorf("expected both blades to be stored, found %d", len(stored.Blades))
+	}
+}
@@ -0,0 +1,112 @@
+package connectors
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// retryable tells whether the failure is a transient network one worth
+// retrying right away, a refused login or an unknown device won't get better
+func retryable(failure string) bool {
+	return failure == model.FailureScan || failure == model.FailureConnection
+}
+
+// breakerAllows tells whether the bmc at the ip can be collected: it isn't
+// dead lettered and its circuit breaker isn't cooling down. Once the cool
+// down is over the next collection decides whether it opens again
+func breakerAllows(db *gorm.DB, ip string) bool {
+	breaker := model.CircuitBreaker{}
+	if err := db.Where("ip = ?", ip).First(&breaker).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.WithFields(log.Fields{"operation": "reading circuit breaker", "ip": ip}).Warn(err)
+		}
+		return true
+	}
+
+	switch breaker.State {
+	case model.BreakerDeadLetter:
+		return false
+	case model.BreakerOpen:
+		return breaker.OpenUntil == nil || time.Now().After(*breaker.OpenUntil)
+	}
+	return true
+}
+
+// recordBreaker updates the circuit breaker of the bmc with the outcome of
+// its collection, retries included. A success removes it, each failure
+// counts: collector.breaker.threshold consecutive ones open it for
+// collector.breaker.cooldown and collector.breaker.dead_letter_after ones
+// dead letter the bmc
+func recordBreaker(db *gorm.DB, attempt *model.CollectionAttempt) {
+	threshold := viper.GetInt("collector.breaker.threshold")
+	if threshold <= 0 || attempt.Failure == model.FailureManagedBlade {
+		return
+	}
+
+	if attempt.Failure == "" {
+		if err := db.Where("ip = ?", attempt.IP).Delete(&model.CircuitBreaker{}).Error; err != nil {
+			log.WithFields(log.Fields{"operation": "resetting circuit breaker", "ip": attempt.IP}).Warn(err)
+		}
+		return
+	}
+
+	breaker := model.CircuitBreaker{}
+	if err := db.Where("ip = ?", attempt.IP).First(&breaker).Error; err != nil && err != gorm.ErrRecordNotFound {
+		log.WithFields(log.Fields{"operation": "reading circuit breaker", "ip": attempt.IP}).Warn(err)
+		return
+	}
+
+	breaker.IP = attempt.IP
+	breaker.Failures++
+	breaker.LastFailure = attempt.Failure
+	breaker.LastError = attempt.Error
+	breaker.State = model.BreakerClosed
+	breaker.OpenUntil = nil
+
+	deadLetterAfter := viper.GetInt("collector.breaker.dead_letter_after")
+	if deadLetterAfter > 0 && breaker.Failures >= deadLetterAfter {
+		breaker.State = model.BreakerDeadLetter
+		log.WithFields(log.Fields{"operation": "collection", "ip": attempt.IP, "failures": breaker.Failures}).Warn("dead lettered")
+	} else if breaker.Failures >= threshold {
+		openUntil := time.Now().Add(viper.GetDuration("collector.breaker.cooldown"))
+		breaker.State = model.BreakerOpen
+		breaker.OpenUntil = &openUntil
+		log.WithFields(log.Fields{"operation": "collection", "ip": attempt.IP, "failures": breaker.Failures}).Warnf("circuit breaker open until %s", openUntil.Format(time.RFC3339))
+	}
+
+	if err := db.Save(&breaker).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "updating circuit breaker", "ip": attempt.IP}).Warn(err)
+	}
+}
+
+// BlockedHosts returns the ips that must not be collected now, the dead
+// lettered ones and the ones whose circuit breaker is cooling down
+func BlockedHosts(db *gorm.DB) (blocked map[string]bool, err error) {
+	var breakers []model.CircuitBreaker
+	err = db.Where("state = ? or (state = ? and open_until > ?)", model.BreakerDeadLetter, model.BreakerOpen, time.Now()).Find(&breakers).Error
+	if err != nil {
+		return blocked, err
+	}
+
+	blocked = make(map[string]bool)
+	for _, breaker := range breakers {
+		blocked[breaker.IP] = true
+	}
+	return blocked, nil
+}
+
+// ResetBreakers removes the circuit breakers of the given ips, or all of
+// them, so they are collected again
+func ResetBreakers(db *gorm.DB, ips []string) (reset int64, err error) {
+	q := db
+	if len(ips) != 1 || ips[0] != "all" {
+		q = q.Where("ip in (?)", ips)
+	}
+	q = q.Delete(&model.CircuitBreaker{})
+	return q.RowsAffected, q.Error
+}
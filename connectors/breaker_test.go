@@ -0,0 +1,79 @@
+package connectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("collector.breaker.threshold", 2)
+	viper.Set("collector.breaker.cooldown", time.Hour)
+	viper.Set("collector.breaker.dead_letter_after", 3)
+	defer func() {
+		viper.Set("collector.breaker.threshold", nil)
+		viper.Set("collector.breaker.cooldown", nil)
+		viper.Set("collector.breaker.dead_letter_after", nil)
+	}()
+
+	fail := &model.CollectionAttempt{IP: "10.0.0.1", Failure: model.FailureConnection, Error: "i/o timeout"}
+
+	recordBreaker(db, fail)
+	if !breakerAllows(db, "10.0.0.1") {
+		t.Errorf("expected a single failure to keep the breaker closed")
+	}
+
+	recordBreaker(db, fail)
+	if breakerAllows(db, "10.0.0.1") {
+		t.Errorf("expected the breaker to be open after the threshold")
+	}
+	blocked, err := BlockedHosts(db)
+	if err != nil || !blocked["10.0.0.1"] {
+		t.Errorf("expected 10.0.0.1 to be blocked, found %v %v", blocked, err)
+	}
+
+	// the cool down is over
+	db.Model(&model.CircuitBreaker{}).Where("ip = ?", "10.0.0.1").Update("open_until", time.Now().Add(-time.Minute))
+	if !breakerAllows(db, "10.0.0.1") {
+		t.Errorf("expected the breaker to let a collection through after the cool down")
+	}
+
+	recordBreaker(db, fail)
+	breaker := model.CircuitBreaker{}
+	db.Where("ip = ?", "10.0.0.1").First(&breaker)
+	if breaker.State != model.BreakerDeadLetter || breaker.Failures != 3 || breaker.LastError != "i/o timeout" || breakerAllows(db, "10.0.0.1") {
+		t.Errorf("expected 10.0.0.1 to be dead lettered, found %+v", breaker)
+	}
+
+	recordBreaker(db, &model.CollectionAttempt{IP: "10.0.0.2", Failure: model.FailureWrongCredentials})
+	recordBreaker(db, &model.CollectionAttempt{IP: "10.0.0.2"})
+	count := 0
+	db.Model(&model.CircuitBreaker{}).Where("ip = ?", "10.0.0.2").Count(&count)
+	if count != 0 {
+		t.Errorf("expected a successful collection to remove the breaker")
+	}
+
+	if reset, err := ResetBreakers(db, []string{"10.0.0.1"}); err != nil || reset != 1 || !breakerAllows(db, "10.0.0.1") {
+		t.Errorf("expected the breaker of 10.0.0.1 to be reset, found %d %v", reset, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for failure, expected := range map[string]bool{
+		model.FailureScan:             true,
+		model.FailureConnection:       true,
+		model.FailureWrongCredentials: false,
+		model.FailureUnknownDevice:    false,
+		"":                            false,
+	} {
+		if retryable(failure) != expected {
+			t.Errorf("%q: expected retryable to be %v", failure, expected)
+		}
+	}
+}
@@ -1,15 +1,17 @@
 package connectors
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/bmc-toolbox/bmclib/discover"
-	"github.com/bmc-toolbox/bmclib/errors"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jinzhu/gorm"
 	"github.com/nats-io/go-nats"
@@ -20,166 +22,284 @@ import (
 
 	"github.com/bmc-toolbox/dora/internal/notification"
 	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/scanner"
 	"github.com/bmc-toolbox/dora/storage"
 )
 
-func collect(input <-chan string, source *string, db *gorm.DB) {
-	bmcUser := viper.GetString("bmc_user")
-	bmcPass := ""
-	if viper.IsSet("bmc_pass") {
-		bmcPass = viper.GetString("bmc_pass")
-	} else {
-		bmcPassFile := viper.GetString("bmc_pass_file")
-		bmcPassBytes, err := ioutil.ReadFile(bmcPassFile)
-		if err != nil {
-			log.WithFields(log.Fields{"operation": "scan"}).Error(err)
+// collect takes hosts from the input of the pool until it's closed or stop
+// is done and collects them, retrying the transient failures. Each host gets
+// the host timeout of the pool, abandon interrupts the ones being collected
+func collect(stop context.Context, abandon context.Context, pool *collectionPool, source *string, db *gorm.DB, credentials CredentialProvider) {
+	for {
+		var host string
+		select {
+		case <-stop.Done():
+			return
+		case ip, ok := <-pool.input:
+			if !ok || stop.Err() != nil {
+				return
+			}
+			host = ip
 		}
-		bmcPass = string(bmcPassBytes)
+
+		if *source != "cli-with-force" && !breakerAllows(db, host) {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Warn("circuit breaker open, skipping")
+			if viper.GetBool("metrics.enabled") {
+				metrics.IncrCounter([]string{"collect.circuit_open"}, 1)
+			}
+			continue
+		}
+
+		pool.working(1)
+		backoff := viper.GetDuration("collector.retry.backoff")
+	retries:
+		for retry := 0; ; retry++ {
+			attempt := collectHost(abandon, pool, host, *source, db, credentials)
+			if attempt.Failure == model.FailureCanceled {
+				break
+			}
+			if !retryable(attempt.Failure) || retry >= viper.GetInt("collector.retry.attempts") {
+				recordBreaker(db, attempt)
+				break
+			}
+
+			log.WithFields(log.Fields{"operation": "collection", "ip": host, "failure": attempt.Failure, "retry": retry + 1}).Warnf("retrying in %s", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-stop.Done():
+				break retries
+			}
+			if backoff *= 2; backoff > viper.GetDuration("collector.retry.max_backoff") {
+				backoff = viper.GetDuration("collector.retry.max_backoff")
+			}
+		}
+		pool.working(-1)
 	}
+}
 
-	for host := range input {
-		log.WithFields(log.Fields{"operation": "scan", "ip": host}).Debug("collection started")
+// collectHost collects the bmc at the ip once and returns the attempt it
+// recorded. Each stage is bound by its own deadline and the one of the host
+func collectHost(ctx context.Context, pool *collectionPool, host string, source string, db *gorm.DB, credentials CredentialProvider) (attempt *model.CollectionAttempt) {
+	log.WithFields(log.Fields{"operation": "scan", "ip": host}).Debug("collection started")
+
+	ctx, cancel := withTimeout(ctx, pool.timeoutKey("host"))
+	defer cancel()
+
+	graphiteKey := "collect.collected_successfully"
+	hintOpts := hintOptsInit(host, db)
+	updateSacMetricFn := scanAndConnectMetricInit()
+	attempt = newCollectionAttempt(host, source)
+	attempt.Pool = pool.name
+
+	query := credentialQuery(host, db)
+	credential := connectCredential(credentials, query)
+	sess := &session{}
+	var conn interface{}
+	err := sess.runStage(ctx, pool, model.CollectionStageScan, func(ctx context.Context) (err error) {
+		conn, err = discover.ScanAndConnect(host, credential.Username, credential.Password, hintOpts...)
+		return err
+	}, func() {
+		// the connection opened past the deadline is of no use
+		closeConn(conn)
+	})
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "scan", "ip": host}).Error(err)
+		graphiteKey = "collect.bmc_scan_failed"
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{graphiteKey}, 1)
+		}
+		recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureScan), err)
+		return attempt
+	}
 
-		graphiteKey := "collect.collected_successfully"
-		hintOpts := hintOptsInit(host, db)
-		updateSacMetricFn := scanAndConnectMetricInit()
+	updateSacMetricFn()
+	attempt.Stage = model.CollectionStageLogin
+	defer sess.close(func() { closeConn(conn) })
 
-		conn, err := discover.ScanAndConnect(host, bmcUser, bmcPass, hintOpts...)
-		if err != nil {
-			log.WithFields(log.Fields{"operation": "scan", "ip": host}).Error(err)
-			graphiteKey = "collect.bmc_scan_failed"
+	if bmc, ok := conn.(devices.Bmc); ok {
+		var credentialID, authMethod string
+		err := sess.runStage(ctx, pool, model.CollectionStageLogin, func(ctx context.Context) (err error) {
+			credentialID, authMethod, err = login(bmc, credentials, query)
+			return err
+		}, nil)
+		if err == ErrLoginFailed {
+			log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+			recordAuthFailure(db, host)
+			graphiteKey = "collect.bmc_wrong_credentials"
 			if viper.GetBool("metrics.enabled") {
 				metrics.IncrCounter([]string{graphiteKey}, 1)
 			}
-			continue
+			recordCollectionAttempt(db, attempt, model.FailureWrongCredentials, err)
+			return attempt
+		} else if err != nil {
+			log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+			graphiteKey = "collect.bmc_connection_failed"
+			if viper.GetBool("metrics.enabled") {
+				metrics.IncrCounter([]string{graphiteKey}, 1)
+			}
+			recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureConnection), err)
+			return attempt
 		}
 
-		updateSacMetricFn()
-
-		if bmc, ok := conn.(devices.Bmc); ok {
-			err = bmc.CheckCredentials()
-			if err == errors.ErrLoginFailed {
-				bmc.UpdateCredentials(
-					viper.GetString(fmt.Sprintf("collector.default.%s.username", bmc.Vendor())),
-					viper.GetString(fmt.Sprintf("collector.default.%s.password", bmc.Vendor())),
-				)
-				err = bmc.CheckCredentials()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-					graphiteKey = "collect.bmc_wrong_credentials"
-					if viper.GetBool("metrics.enabled") {
-						metrics.IncrCounter([]string{graphiteKey}, 1)
-					}
-					continue
-				}
-			} else if err != nil {
-				log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_connection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
+		attempt.Stage = model.CollectionStageDetect
+		var isBlade bool
+		err = sess.runStage(ctx, pool, model.CollectionStageDetect, func(ctx context.Context) (err error) {
+			isBlade, err = bmc.IsBlade()
+			return err
+		}, nil)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
+			graphiteKey = "collect.bmc_is_blade_detection_failed"
+			if viper.GetBool("metrics.enabled") {
+				metrics.IncrCounter([]string{graphiteKey}, 1)
 			}
+			recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureBladeDetection), err)
+			return attempt
+		}
 
-			isBlade, err := bmc.IsBlade()
+		if isBlade && source != "cli-with-force" {
+			var chassisSerial string
+			err := sess.runStage(ctx, pool, model.CollectionStageDetect, func(ctx context.Context) (err error) {
+				chassisSerial, err = bmc.ChassisSerial()
+				return err
+			}, nil)
 			if err != nil {
 				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_is_blade_detection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
+				recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureChassisSerial), err)
+				return attempt
 			}
 
-			if isBlade && *source != "cli-with-force" {
-				chassisSerial, err := bmc.ChassisSerial()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-					continue
-				}
-
-				chassis := model.Chassis{}
-				db.Where("serial = ?", chassisSerial).First(&chassis)
+			chassis := model.Chassis{}
+			db.Where("serial = ?", chassisSerial).First(&chassis)
 
-				if chassis.Managed {
-					log.WithFields(log.Fields{"operation": "detection", "ip": host}).Debug("we don't want to scan blades directly since the chassis does it for us")
-					continue
-				}
+			if chassis.Managed {
+				log.WithFields(log.Fields{"operation": "detection", "ip": host}).Debug("we don't want to scan blades directly since the chassis does it for us")
+				recordCollectionAttempt(db, attempt, model.FailureManagedBlade, nil)
+				return attempt
 			}
+		}
 
-			err = collectBmc(bmc)
-			if err != nil {
-				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
-				graphiteKey = "collect.bmc_collection_failed"
-			}
+		attempt.Stage = model.CollectionStageCollect
+		var assetType, serial string
+		err = sess.runStage(ctx, pool, model.CollectionStageCollect, func(ctx context.Context) (err error) {
+			assetType, serial, err = collectBmc(ctx, bmc, credentialID, authMethod)
+			return err
+		}, nil)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
+			graphiteKey = "collect.bmc_collection_failed"
+			recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureCollection), err)
+		} else {
+			attempt.AssetType, attempt.AssetSerial = assetType, serial
+			recordCollectionAttempt(db, attempt, "", nil)
+		}
 
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
-		} else if bmc, ok := conn.(devices.Cmc); ok {
-			err = bmc.CheckCredentials()
-			if err == errors.ErrLoginFailed {
-				bmc.UpdateCredentials(
-					viper.GetString(fmt.Sprintf("collector.default.%s.username", bmc.Vendor())),
-					viper.GetString(fmt.Sprintf("collector.default.%s.password", bmc.Vendor())),
-				)
-				err = bmc.CheckCredentials()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-					graphiteKey = "collect.cmc_wrong_credentials"
-					if viper.GetBool("metrics.enabled") {
-						metrics.IncrCounter([]string{graphiteKey}, 1)
-					}
-					continue
-				}
-			} else if err != nil {
-				log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
-				graphiteKey = "collect.cmc_connection_failed"
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-				continue
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
+	} else if bmc, ok := conn.(devices.Cmc); ok {
+		var credentialID, authMethod string
+		err := sess.runStage(ctx, pool, model.CollectionStageLogin, func(ctx context.Context) (err error) {
+			credentialID, authMethod, err = login(bmc, credentials, query)
+			return err
+		}, nil)
+		if err == ErrLoginFailed {
+			log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+			recordAuthFailure(db, host)
+			graphiteKey = "collect.cmc_wrong_credentials"
+			if viper.GetBool("metrics.enabled") {
+				metrics.IncrCounter([]string{graphiteKey}, 1)
+			}
+			recordCollectionAttempt(db, attempt, model.FailureWrongCredentials, err)
+			return attempt
+		} else if err != nil {
+			log.WithFields(log.Fields{"operation": "connection", "ip": host}).Error(err)
+			graphiteKey = "collect.cmc_connection_failed"
+			if viper.GetBool("metrics.enabled") {
+				metrics.IncrCounter([]string{graphiteKey}, 1)
 			}
+			recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureConnection), err)
+			return attempt
+		}
 
-			err := collectCmc(bmc, bmcUser, bmcPass)
+		attempt.Stage = model.CollectionStageCollect
+		var chassis *model.Chassis
+		err = sess.runStage(ctx, pool, model.CollectionStageCollect, func(ctx context.Context) (err error) {
+			chassis, err = snapshotCmc(bmc, credentialID, authMethod, db)
+			return err
+		}, nil)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
+			graphiteKey = "collect.cmc_collection_failed"
+			recordCollectionAttempt(db, attempt, stageFailure(err, model.FailureCollection), err)
+		} else if chassis == nil {
+			recordCollectionAttempt(db, attempt, "", nil)
+		} else {
+			attempt.Stage = model.CollectionStageEnrich
+			attempt.AssetType, attempt.AssetSerial = "chassis", chassis.Serial
+			slots, err := enrichAndStoreChassis(ctx, pool, chassis, db, credentials)
+			attempt.BladesEnriched, attempt.BladesSkipped, attempt.BladesFailed, attempt.BladesTimedOut = joinSlots(slots.enriched), joinSlots(slots.skipped), joinSlots(slots.failed), joinSlots(slots.timedOut)
 			if err != nil {
 				log.WithFields(log.Fields{"operation": "collection", "ip": host}).Error(err)
 				graphiteKey = "collect.cmc_collection_failed"
+				recordCollectionAttempt(db, attempt, model.FailureCollection, err)
+			} else {
+				recordCollectionAttempt(db, attempt, "", nil)
 			}
-
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
-		} else {
-			log.WithFields(log.Fields{"operation": "collection", "ip": host}).Debug("unknown hardware skipping")
-			graphiteKey = "collect.unknown_device"
-		}
-		// send metric which is not protected by "continue"
-		if viper.GetBool("metrics.enabled") {
-			metrics.IncrCounter([]string{graphiteKey}, 1)
 		}
+
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Info("success")
+	} else {
+		log.WithFields(log.Fields{"operation": "collection", "ip": host}).Debug("unknown hardware skipping")
+		graphiteKey = "collect.unknown_device"
+		recordCollectionAttempt(db, attempt, model.FailureUnknownDevice, nil)
+	}
+	// send metric which is not protected by "continue"
+	if viper.GetBool("metrics.enabled") {
+		metrics.IncrCounter([]string{graphiteKey}, 1)
 	}
+	return attempt
 }
 
 // DataCollection collects the data of all given ips
 func DataCollection(ips []string, source string) {
-	concurrency := viper.GetInt("collector.concurrency")
-
-	cc := make(chan string, concurrency)
 	wg := sync.WaitGroup{}
 	db := storage.InitDB()
 
-	wg.Add(concurrency)
-	for i := 0; i < concurrency; i++ {
-		go func(input <-chan string, source *string, db *gorm.DB, wg *sync.WaitGroup) {
-			defer wg.Done()
-			collect(input, source, db)
-		}(cc, &source, db, &wg)
+	exclusions, err := scanner.NewExclusionChecker()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading exclusions"}).Error(err)
+		return
 	}
 
+	credentials, err := NewCredentialProvider()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading credentials"}).Error(err)
+		return
+	}
+
+	pools, err := newCollectionPools()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading collection pools"}).Error(err)
+		return
+	}
+
+	stop, abandon, cancel := drainOnSignal()
+	defer cancel()
+
+	pools.start(stop, abandon, &source, db, credentials, &wg)
+
 	if ips[0] == "all" {
 		var hosts []model.ScannedPort
-		if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open'").Find(&hosts).Error; err != nil {
+		if err := db.Where("port = 443 and protocol = 'tcp' and state = 'open' and stale = ?", false).Find(&hosts).Error; err != nil {
 			log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": "all"}).Error(err)
 		} else {
 			for _, host := range hosts {
-				cc <- host.IP
+				if exclusions.Excluded(host.IP) {
+					log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": host.IP}).Warn("excluded ip, skipping")
+					continue
+				}
+				if !pools.enqueue(stop, host.IP, db) {
+					break
+				}
 			}
 		}
 	} else {
@@ -193,39 +313,71 @@ func DataCollection(ips []string, source string) {
 					continue
 				}
 				ip = lookup[0]
+			} else {
+				ip = parsedIP.String()
 			}
 
-			if err := db.Where("ip = ? and port = 443 and protocol = 'tcp' and state = 'open'", ip).Find(&host).Error; err != nil {
+			if err := db.Where("ip = ? and port = 443 and protocol = 'tcp' and state = 'open' and stale = ?", ip, false).Find(&host).Error; err != nil {
 				log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": ip}).Error(err)
 				continue
 			}
-			cc <- host.IP
+			if exclusions.Excluded(host.IP) {
+				log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": host.IP}).Warn("excluded ip, skipping")
+				continue
+			}
+			if !pools.enqueue(stop, host.IP, db) {
+				break
+			}
 		}
 	}
 
-	close(cc)
+	pools.close()
 	wg.Wait()
+	waitPending()
 }
 
-// DataCollectionWorker collects the data of all given ips
+// DataCollectionWorker collects the ips received from the queue until
+// SIGINT or SIGTERM, then drains the hosts being collected
 func DataCollectionWorker() {
 	nc, err := nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
 	if err != nil {
 		log.Fatalf("Subscriber unable to connect: %v\n", err)
 	}
 
-	concurrency := viper.GetInt("collector.concurrency")
-	cc := make(chan string, concurrency)
 	db := storage.InitDB()
 	source := "worker"
 
-	for i := 0; i < concurrency; i++ {
-		go func(input <-chan string, source *string, db *gorm.DB) {
-			collect(input, source, db)
-		}(cc, &source, db)
+	credentials, err := NewCredentialProvider()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading credentials"}).Fatal(err)
+	}
+
+	pools, err := newCollectionPools()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading collection pools"}).Fatal(err)
+	}
+
+	// the exclusions are reloaded once older than collector.worker.source_cache_ttl or on SIGHUP
+	exclusions := scanner.NewSourceCache(viper.GetDuration("collector.worker.source_cache_ttl"))
+	if _, err := exclusions.ExclusionChecker(); err != nil {
+		log.WithFields(log.Fields{"operation": "loading exclusions"}).Fatal(err)
 	}
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.WithFields(log.Fields{"operation": "loading exclusions"}).Info("reloading the subnet source")
+			exclusions.Reset()
+		}
+	}()
+
+	stop, abandon, cancel := drainOnSignal()
+	defer cancel()
 
-	_, err = nc.QueueSubscribe("dora::collect", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
+	wg := sync.WaitGroup{}
+	pools.start(stop, abandon, &source, db, credentials, &wg)
+
+	sub, err := nc.QueueSubscribe("dora::collect", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
 		ip := string(msg.Data)
 		parsedIP := net.ParseIP(ip)
 		if parsedIP == nil {
@@ -236,7 +388,20 @@ func DataCollectionWorker() {
 			}
 			ip = lookup[0]
 		}
-		cc <- ip
+
+		checker, err := exclusions.ExclusionChecker()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "loading exclusions", "ip": ip}).Error(err)
+			return
+		}
+		if checker.Excluded(ip) {
+			log.WithFields(log.Fields{"operation": "retrieving scanned hosts", "ip": ip}).Warn("excluded ip, skipping")
+			return
+		}
+
+		if !pools.enqueue(stop, ip, db) {
+			log.WithFields(log.Fields{"operation": "collection", "ip": ip}).Warn("draining, dropping the message")
+		}
 	})
 	if err != nil {
 		log.WithFields(log.Fields{"operation": "error subscribing to the queue"}).Fatal(err)
@@ -246,39 +411,58 @@ func DataCollectionWorker() {
 	}
 
 	log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": "dora::collect"}).Info("subscribed to queue")
-}
 
-func collectBmc(bmc devices.Bmc) (err error) {
-	defer bmc.Close(nil)
+	<-stop.Done()
+	if err := sub.Unsubscribe(); err != nil {
+		log.WithFields(log.Fields{"operation": "unsubscribing from the queue"}).Error(err)
+	}
+	wg.Wait()
+	waitPending()
+	nc.Close()
+	log.WithFields(log.Fields{"operation": "shutdown"}).Info("collection drained")
+}
 
-	serial, err := bmc.Serial()
+func collectBmc(ctx context.Context, bmc devices.Bmc, credentialID string, authMethod string) (assetType string, serial string, err error) {
+	serial, err = bmc.Serial()
 	if err != nil {
-		return err
+		return assetType, serial, err
 	}
 
 	if serial == "" || serial == "[unknown]" || serial == "0000000000" || serial == "_" {
-		return ErrInvalidSerial
+		return assetType, serial, ErrInvalidSerial
 	}
 
 	isBlade, err := bmc.IsBlade()
 	if err != nil {
-		return err
+		return assetType, serial, err
+	}
+
+	assetType = "discretes"
+	if isBlade {
+		assetType = "blades"
 	}
 
 	db := storage.InitDB()
 	if isBlade {
 		server, err := bmc.ServerSnapshot()
 		if err != nil {
-			return err
+			return assetType, serial, err
+		}
+
+		// nothing is stored once the deadline has passed
+		if err = ctx.Err(); err != nil {
+			return assetType, serial, err
 		}
 
 		b, ok := server.(*devices.Blade)
 		if !ok {
-			return fmt.Errorf("unable to read devices.Blade")
+			return assetType, serial, fmt.Errorf("unable to read devices.Blade")
 		}
 
 		blade := model.NewBladeFromDevice(b)
 		blade.BmcAuth = true
+		blade.CredentialID = credentialID
+		blade.AuthMethod = authMethod
 		blade.BmcWEBReachable = true
 
 		db.Where(model.Chassis{Serial: blade.ChassisSerial}).FirstOrCreate(&model.Chassis{})
@@ -298,12 +482,12 @@ func collectBmc(bmc devices.Bmc) (err error) {
 		bladeStorage := storage.NewBladeStorage(db)
 		existingData, err := bladeStorage.GetOne(blade.Serial)
 		if err != nil && err != gorm.ErrRecordNotFound {
-			return err
+			return assetType, serial, err
 		}
 
 		_, err = bladeStorage.UpdateOrCreate(blade)
 		if err != nil {
-			return err
+			return assetType, serial, err
 		}
 
 		if len(blade.Diff(&existingData)) != 0 {
@@ -313,21 +497,28 @@ func collectBmc(bmc devices.Bmc) (err error) {
 
 		err = bladeStorage.RemoveOldRefs(blade)
 		if err != nil {
-			return err
+			return assetType, serial, err
 		}
 	} else {
 		server, err := bmc.ServerSnapshot()
 		if err != nil {
-			return err
+			return assetType, serial, err
+		}
+
+		// nothing is stored once the deadline has passed
+		if err = ctx.Err(); err != nil {
+			return assetType, serial, err
 		}
 
 		b, ok := server.(*devices.Discrete)
 		if !ok {
-			return fmt.Errorf("unable to read devices.Discrete")
+			return assetType, serial, fmt.Errorf("unable to read devices.Discrete")
 		}
 
 		discrete := model.NewDiscreteFromDevice(b)
 		discrete.BmcAuth = true
+		discrete.CredentialID = credentialID
+		discrete.AuthMethod = authMethod
 		discrete.BmcWEBReachable = true
 
 		var scans []model.ScannedPort
@@ -345,12 +536,12 @@ func collectBmc(bmc devices.Bmc) (err error) {
 		discreteStorage := storage.NewDiscreteStorage(db)
 		existingData, err := discreteStorage.GetOne(discrete.Serial)
 		if err != nil && err != gorm.ErrRecordNotFound {
-			return err
+			return assetType, serial, err
 		}
 
 		_, err = discreteStorage.UpdateOrCreate(discrete)
 		if err != nil {
-			return err
+			return assetType, serial, err
 		}
 
 		if len(discrete.Diff(&existingData)) != 0 {
@@ -360,29 +551,29 @@ func collectBmc(bmc devices.Bmc) (err error) {
 
 		err = discreteStorage.RemoveOldRefs(discrete)
 		if err != nil {
-			return err
+			return assetType, serial, err
 		}
 	}
 
-	return nil
+	return assetType, serial, nil
 }
 
-func collectCmc(bmc devices.Cmc, bmcUser string, bmcPass string) (err error) {
-	defer bmc.Close()
-
+// snapshotCmc reads the chassis and what the cmc tells about its blades, a
+// standby cmc has nothing to tell
+func snapshotCmc(bmc devices.Cmc, credentialID string, authMethod string, db *gorm.DB) (chassis *model.Chassis, err error) {
 	if !bmc.IsActive() {
-		return err
+		return chassis, nil
 	}
 
-	db := storage.InitDB()
-
 	ch, err := bmc.ChassisSnapshot()
 	if err != nil {
-		return err
+		return chassis, err
 	}
 
-	chassis := model.NewChassisFromDevice(ch)
+	chassis = model.NewChassisFromDevice(ch)
 	chassis.BmcAuth = true
+	chassis.CredentialID = credentialID
+	chassis.AuthMethod = authMethod
 	chassis.Managed = true
 	var scans []model.ScannedPort
 	db.Where("ip = ?", chassis.BmcAddress).Find(&scans)
@@ -394,113 +585,23 @@ func collectCmc(bmc devices.Cmc, bmcUser string, bmcPass string) (err error) {
 		}
 	}
 
-	for _, blade := range chassis.Blades {
-		hintOpts := hintOptsInit(blade.BmcAddress, db)
-		updateSacMetricFn := scanAndConnectMetricInit()
-
-		if conn, err := discover.ScanAndConnect(
-			blade.BmcAddress,
-			bmcUser,
-			bmcPass,
-			hintOpts...,
-		); err == nil {
-
-			updateSacMetricFn()
-			if b, ok := conn.(devices.Bmc); ok {
-				err = b.CheckCredentials()
-				if err == errors.ErrLoginFailed {
-					b.UpdateCredentials(
-						viper.GetString(fmt.Sprintf("collector.default.%s.username", b.Vendor())),
-						viper.GetString(fmt.Sprintf("collector.default.%s.password", b.Vendor())),
-					)
-					err = b.CheckCredentials()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "connection", "ip": blade.BmcAddress}).Error(err)
-						continue
-					}
-				} else if err != nil {
-					log.WithFields(log.Fields{"operation": "connection", "ip": blade.BmcAddress}).Error(err)
-					continue
-				}
-
-				blade.BmcAuth = true
-				blade.BmcWEBReachable = true
-
-				var scans []model.ScannedPort
-				db.Where("ip = ?", blade.BmcAddress).Find(&scans)
-				for _, scan := range scans {
-					if scan.Port == 22 && scan.Protocol == "tcp" && scan.State == "open" {
-						blade.BmcSSHReachable = true
-					} else if scan.Port == 443 && scan.Protocol == "tcp" && scan.State == "open" {
-						blade.BmcWEBReachable = true
-					} else if scan.Port == 623 && scan.Protocol == "ipmi" && scan.State == "open" {
-						blade.BmcIpmiReachable = true
-					}
-				}
-
-				blade.BmcType = b.HardwareType()
-
-				blade.Processor, blade.ProcessorCount, blade.ProcessorCoreCount, blade.ProcessorThreadCount, err = b.CPU()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading cpu data", "ip": blade.BmcAddress, "name": blade.Name, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
-
-				blade.Memory, err = b.Memory()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading memory data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
-
-				blade.BmcLicenceType, blade.BmcLicenceStatus, err = b.License()
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "reading license data", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-				}
-
-				if len(blade.Nics) == 0 {
-					nics, err := b.Nics()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "reading nice", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-					} else {
-						for _, nic := range nics {
-							blade.Nics = append(blade.Nics, &model.Nic{
-								MacAddress:  nic.MacAddress,
-								Name:        nic.Name,
-								BladeSerial: blade.Serial,
-							})
-						}
-					}
-				}
+	return chassis, nil
+}
 
-				if len(blade.Disks) == 0 {
-					disks, err := b.Disks()
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "reading disks", "ip": blade.BmcAddress, "serial": blade.Serial, "type": "chassis"}).Warning(err)
-					} else {
-						for pos, disk := range disks {
-							if disk.Serial == "" {
-								disk.Serial = fmt.Sprintf("%s-failed-%d", blade.Serial, pos)
-							}
-
-							blade.Disks = append(blade.Disks, &model.Disk{
-								Serial:      disk.Serial,
-								Size:        disk.Size,
-								Status:      disk.Status,
-								Model:       disk.Model,
-								Location:    disk.Location,
-								Type:        disk.Type,
-								FwVersion:   disk.FwVersion,
-								BladeSerial: blade.Serial,
-							})
-						}
-					}
-				}
+// enrichAndStoreChassis enriches the blades of the chassis within
+// collector.timeouts.enrich, apart from the collect stage, and then stores
+// the chassis whatever happened to its blades
+func enrichAndStoreChassis(ctx context.Context, pool *collectionPool, chassis *model.Chassis, db *gorm.DB, credentials CredentialProvider) (slots bladeSlots, err error) {
+	enrichCtx, cancel := withTimeout(ctx, pool.timeoutKey(model.CollectionStageEnrich))
+	slots = enrichBlades(enrichCtx, pool, chassis, db, credentials)
+	cancel()
 
-				if err == nil {
-					log.WithFields(log.Fields{"operation": "collection", "ip": blade.BmcAddress}).Info("success")
-				}
-			}
-		}
-	}
+	return slots, storeChassis(chassis, db)
+}
 
+// storeChassis stores the chassis with its blades and removes what it no
+// longer has
+func storeChassis(chassis *model.Chassis, db *gorm.DB) (err error) {
 	chassisStorage := storage.NewChassisStorage(db)
 	existingData, err := chassisStorage.GetOne(chassis.Serial)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -0,0 +1,415 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/errors"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// Credential is a candidate username and password of a bmc, only its id is
+// ever logged or stored
+type Credential struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// primary is the first candidate, regardless of the last one that worked
+	primary bool
+	// vendorDefault is a collector.default.<vendor> credential
+	vendorDefault bool
+}
+
+// authMethod tells how the bmc was logged into with the credential
+func (c *Credential) authMethod() string {
+	switch {
+	case c.vendorDefault:
+		return model.AuthDefaultVendor
+	case c.primary:
+		return model.AuthPrimary
+	}
+	return model.AuthFallback
+}
+
+// CredentialQuery describes the bmc the credentials are needed for, the
+// vendor is only known once connected and the model once collected
+type CredentialQuery struct {
+	IP     string
+	Site   string
+	Vendor string
+	Model  string
+	// LastID is the id of the credential that worked the last time, tried first
+	LastID string
+}
+
+// CredentialProvider resolves the ordered candidate credentials of a bmc
+type CredentialProvider interface {
+	Candidates(q *CredentialQuery) ([]*Credential, error)
+}
+
+// NewCredentialProvider returns the provider of collector.credentials.backend,
+// file or exec, followed by the bmc_user and collector.default.<vendor> ones
+func NewCredentialProvider() (CredentialProvider, error) {
+	providers := chainedProvider{}
+
+	switch backend := viper.GetString("collector.credentials.backend"); backend {
+	case "":
+	case "file":
+		p, err := newFileProvider(viper.GetString("collector.credentials.file"))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	case "exec":
+		command := viper.GetString("collector.credentials.command")
+		if command == "" {
+			return nil, fmt.Errorf("collector.credentials.command is required by the exec backend")
+		}
+		providers = append(providers, &execProvider{command: command, timeout: viper.GetDuration("collector.credentials.timeout")})
+	default:
+		return nil, fmt.Errorf("unknown credentials backend: %s", backend)
+	}
+
+	providers = append(providers, configProvider{})
+	return providers, nil
+}
+
+// chainedProvider returns the candidates of each provider in turn, the
+// credential that worked the last time first
+type chainedProvider []CredentialProvider
+
+// Candidates to satisfy the CredentialProvider interface
+func (c chainedProvider) Candidates(q *CredentialQuery) (candidates []*Credential, err error) {
+	seen := make(map[string]bool)
+	for _, p := range c {
+		credentials, err := p.Candidates(q)
+		if err != nil {
+			return candidates, err
+		}
+		for _, credential := range credentials {
+			if seen[credential.ID] {
+				continue
+			}
+			seen[credential.ID] = true
+			candidates = append(candidates, credential)
+		}
+	}
+
+	if len(candidates) != 0 {
+		candidates[0].primary = true
+	}
+	for i, credential := range candidates {
+		if credential.ID == q.LastID && i != 0 {
+			candidates = append([]*Credential{credential}, append(candidates[:i:i], candidates[i+1:]...)...)
+			break
+		}
+	}
+
+	return candidates, nil
+}
+
+// configProvider returns the historical bmc_user with bmc_pass or
+// bmc_pass_file, then collector.default.<vendor> when
+// collector.try_default_credentials is set
+type configProvider struct{}
+
+// Candidates to satisfy the CredentialProvider interface
+func (configProvider) Candidates(q *CredentialQuery) (candidates []*Credential, err error) {
+	if user := viper.GetString("bmc_user"); user != "" {
+		password := viper.GetString("bmc_pass")
+		if !viper.IsSet("bmc_pass") && viper.IsSet("bmc_pass_file") {
+			content, err := ioutil.ReadFile(viper.GetString("bmc_pass_file"))
+			if err != nil {
+				return candidates, err
+			}
+			// files usually end with a newline, the bmc wouldn't accept it
+			password = strings.TrimSpace(string(content))
+		}
+		candidates = append(candidates, &Credential{ID: "default", Username: user, Password: password})
+	}
+
+	if q.Vendor != "" && viper.GetBool("collector.try_default_credentials") {
+		prefix := fmt.Sprintf("collector.default.%s", q.Vendor)
+		if user := viper.GetString(prefix + ".username"); user != "" {
+			candidates = append(candidates, &Credential{ID: "default-" + strings.ToLower(q.Vendor), Username: user, Password: viper.GetString(prefix + ".password"), vendorDefault: true})
+		}
+	}
+
+	return candidates, nil
+}
+
+// fileCredential is an entry of the credentials file, each selector left
+// empty matches any bmc
+type fileCredential struct {
+	ID           string   `yaml:"id"`
+	Username     string   `yaml:"username"`
+	Password     string   `yaml:"password"`
+	PasswordFile string   `yaml:"password_file"`
+	PasswordEnv  string   `yaml:"password_env"`
+	Sites        []string `yaml:"sites"`
+	Vendors      []string `yaml:"vendors"`
+	Models       []string `yaml:"models"`
+	Networks     []string `yaml:"networks"`
+
+	networks []*net.IPNet
+}
+
+// fileProvider reads its credentials from a yaml file, the most specific
+// entries matching the bmc come first, then the file order
+type fileProvider struct {
+	credentials []*fileCredential
+}
+
+func newFileProvider(path string) (*fileProvider, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		Credentials []*fileCredential `yaml:"credentials"`
+	}{}
+	if err = yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	for _, c := range data.Credentials {
+		if c.ID == "" {
+			return nil, fmt.Errorf("%s: credential of %s without id", path, c.Username)
+		}
+		for _, network := range c.Networks {
+			_, ipNet, err := net.ParseCIDR(network)
+			if err != nil {
+				return nil, fmt.Errorf("%s: credential %s: %w", path, c.ID, err)
+			}
+			c.networks = append(c.networks, ipNet)
+		}
+	}
+
+	return &fileProvider{credentials: data.Credentials}, nil
+}
+
+// Candidates to satisfy the CredentialProvider interface
+func (f *fileProvider) Candidates(q *CredentialQuery) (candidates []*Credential, err error) {
+	type match struct {
+		credential  *fileCredential
+		specificity int
+	}
+
+	var matches []match
+	for _, c := range f.credentials {
+		if specificity, ok := c.matches(q); ok {
+			matches = append(matches, match{c, specificity})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].specificity > matches[j].specificity })
+
+	for _, m := range matches {
+		password, err := m.credential.password()
+		if err != nil {
+			return candidates, err
+		}
+		candidates = append(candidates, &Credential{ID: m.credential.ID, Username: m.credential.Username, Password: password})
+	}
+	return candidates, nil
+}
+
+// matches tells whether every selector of the credential matches the bmc and
+// how many of them are set
+func (c *fileCredential) matches(q *CredentialQuery) (specificity int, ok bool) {
+	for _, selector := range []struct {
+		values []string
+		value  string
+	}{{c.Sites, q.Site}, {c.Vendors, q.Vendor}, {c.Models, q.Model}} {
+		if len(selector.values) == 0 {
+			continue
+		}
+		if !containsFold(selector.values, selector.value) {
+			return 0, false
+		}
+		specificity++
+	}
+
+	if len(c.networks) != 0 {
+		ip := net.ParseIP(q.IP)
+		for _, ipNet := range c.networks {
+			if ip != nil && ipNet.Contains(ip) {
+				return specificity + 1, true
+			}
+		}
+		return 0, false
+	}
+
+	return specificity, true
+}
+
+func (c *fileCredential) password() (string, error) {
+	switch {
+	case c.PasswordFile != "":
+		content, err := ioutil.ReadFile(c.PasswordFile)
+		return strings.TrimRight(string(content), "\r\n"), err
+	case c.PasswordEnv != "":
+		password, ok := os.LookupEnv(c.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("credential %s: %s isn't set", c.ID, c.PasswordEnv)
+		}
+		return password, nil
+	}
+	return c.Password, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// execProvider runs a helper command with the bmc in its environment,
+// DORA_BMC_IP, DORA_BMC_SITE, DORA_BMC_VENDOR and DORA_BMC_MODEL, which
+// prints the candidates as a json list of id, username and password
+type execProvider struct {
+	command string
+	timeout time.Duration
+}
+
+// Candidates to satisfy the CredentialProvider interface
+func (e *execProvider) Candidates(q *CredentialQuery) (candidates []*Credential, err error) {
+	ctx := context.Background()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, e.command)
+	cmd.Env = append(os.Environ(),
+		"DORA_BMC_IP="+q.IP,
+		"DORA_BMC_SITE="+q.Site,
+		"DORA_BMC_VENDOR="+q.Vendor,
+		"DORA_BMC_MODEL="+q.Model,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return candidates, fmt.Errorf("credentials helper %s: %w: %s", e.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err = json.Unmarshal(out, &candidates); err != nil {
+		return candidates, fmt.Errorf("credentials helper %s: %w", e.command, err)
+	}
+	for _, c := range candidates {
+		if c.ID == "" {
+			return nil, fmt.Errorf("credentials helper %s: credential of %s without id", e.command, c.Username)
+		}
+	}
+	return candidates, nil
+}
+
+// credentialChecker is the part of devices.Bmc and devices.Cmc used to log in
+type credentialChecker interface {
+	CheckCredentials() error
+	UpdateCredentials(string, string)
+	Vendor() string
+}
+
+// login tries the candidate credentials of the bmc in order until one is
+// accepted and returns its id and how it logged in. A failure other than a
+// refused login is returned right away
+func login(conn credentialChecker, provider CredentialProvider, q *CredentialQuery) (id string, authMethod string, err error) {
+	q.Vendor = conn.Vendor()
+	candidates, err := provider.Candidates(q)
+	if err != nil {
+		return id, authMethod, err
+	}
+
+	for _, credential := range candidates {
+		conn.UpdateCredentials(credential.Username, credential.Password)
+		err = conn.CheckCredentials()
+		if err == nil {
+			if credential.vendorDefault {
+				log.WithFields(log.Fields{"operation": "connection", "ip": q.IP, "credential": credential.ID}).Warn("logged in with the vendor default credentials")
+			}
+			return credential.ID, credential.authMethod(), nil
+		}
+		if err != errors.ErrLoginFailed {
+			return id, authMethod, err
+		}
+		log.WithFields(log.Fields{"operation": "connection", "ip": q.IP, "credential": credential.ID}).Debug(err)
+	}
+
+	return id, model.AuthFailed, ErrLoginFailed
+}
+
+// recordAuthFailure marks the assets already collected from the bmc as no
+// longer accepting any of the credentials
+func recordAuthFailure(db *gorm.DB, ip string) {
+	for _, asset := range []interface{}{&model.Chassis{}, &model.Blade{}, &model.Discrete{}} {
+		if err := db.Model(asset).Where("bmc_address = ?", ip).Updates(map[string]interface{}{"auth_method": model.AuthFailed, "bmc_auth": false}).Error; err != nil {
+			log.WithFields(log.Fields{"operation": "recording authentication failure", "ip": ip}).Warn(err)
+		}
+	}
+}
+
+// credentialQuery describes the bmc at the ip with what is already known of
+// it: the site it was scanned in and the model and the credential of the
+// asset last collected from it
+func credentialQuery(ip string, db *gorm.DB) *CredentialQuery {
+	q := &CredentialQuery{IP: ip}
+
+	var port model.ScannedPort
+	if err := db.Select("site").Where("ip = ?", ip).First(&port).Error; err == nil {
+		q.Site = port.Site
+	}
+
+	for _, asset := range []interface{}{&model.Chassis{}, &model.Blade{}, &model.Discrete{}} {
+		rows, err := db.Model(asset).Select("model, credential_id").Where("bmc_address = ?", ip).Order("updated_at desc").Limit(1).Rows()
+		if err != nil {
+			continue
+		}
+		if rows.Next() {
+			var assetModel, credentialID string
+			if rows.Scan(&assetModel, &credentialID) == nil {
+				q.Model, q.LastID = assetModel, credentialID
+			}
+		}
+		rows.Close()
+		if q.LastID != "" {
+			break
+		}
+	}
+
+	return q
+}
+
+// connectCredential returns the credential the connection is opened with,
+// before the vendor is known
+func connectCredential(provider CredentialProvider, q *CredentialQuery) *Credential {
+	candidates, err := provider.Candidates(q)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading credentials", "ip": q.IP}).Error(err)
+	}
+	if len(candidates) == 0 {
+		return &Credential{}
+	}
+	return candidates[0]
+}
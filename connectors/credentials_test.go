@@ -0,0 +1,216 @@
+package connectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/errors"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func credentialIDs(candidates []*Credential) (ids []string) {
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("DORA_TEST_PASSWORD", "from-env")
+	defer os.Unsetenv("DORA_TEST_PASSWORD")
+
+	content := []byte(`credentials:
+  - id: everywhere
+    username: admin
+    password: admin
+  - id: ams4
+    username: root
+    password_env: DORA_TEST_PASSWORD
+    sites: [ams4]
+  - id: ams4-dell
+    username: root
+    password_file: ` + filepath.Join(dir, "secret") + `
+    sites: [ams4]
+    vendors: [dell]
+  - id: lab
+    username: lab
+    password: lab
+    networks: [10.99.0.0/16]
+`)
+	path := filepath.Join(dir, "credentials.yaml")
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newFileProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		query *CredentialQuery
+		ids   []string
+	}{
+		{&CredentialQuery{IP: "10.0.0.1", Site: "ams4"}, []string{"ams4", "everywhere"}},
+		{&CredentialQuery{IP: "10.0.0.1", Site: "ams4", Vendor: "Dell"}, []string{"ams4-dell", "ams4", "everywhere"}},
+		{&CredentialQuery{IP: "10.99.0.1", Site: "lhr1"}, []string{"lab", "everywhere"}},
+	}
+
+	for _, tc := range tt {
+		candidates, err := p.Candidates(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := credentialIDs(candidates); !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%+v: expected %v, found %v", tc.query, tc.ids, ids)
+		}
+	}
+
+	candidates, _ := p.Candidates(&CredentialQuery{Site: "ams4", Vendor: "dell"})
+	if candidates[0].Password != "s3cret" || candidates[1].Password != "from-env" {
+		t.Errorf("expected the passwords of the file and the environment, found %q and %q", candidates[0].Password, candidates[1].Password)
+	}
+}
+
+func TestChainedProvider(t *testing.T) {
+	viper.Set("bmc_user", "Priest")
+	viper.Set("bmc_pass", "Wololo")
+	viper.Set("collector.default.dell.username", "root")
+	viper.Set("collector.default.dell.password", "calvin")
+	viper.Set("collector.try_default_credentials", true)
+	defer func() {
+		viper.Set("collector.try_default_credentials", nil)
+		viper.Set("bmc_user", nil)
+		viper.Set("bmc_pass", nil)
+		viper.Set("collector.default.dell.username", nil)
+		viper.Set("collector.default.dell.password", nil)
+	}()
+
+	dir, err := ioutil.TempDir("", "dora-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	helper := filepath.Join(dir, "helper")
+	script := []byte("#!/bin/sh\necho \"[{\\\"id\\\": \\\"vault-$DORA_BMC_SITE\\\", \\\"username\\\": \\\"vault\\\", \\\"password\\\": \\\"$DORA_BMC_IP\\\"}]\"\n")
+	if err := ioutil.WriteFile(helper, script, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	p := chainedProvider{&execProvider{command: helper}, configProvider{}}
+	candidates, err := p.Candidates(&CredentialQuery{IP: "10.0.0.1", Site: "ams4", Vendor: "Dell", LastID: "default-dell"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := credentialIDs(candidates); !reflect.DeepEqual(ids, []string{"default-dell", "vault-ams4", "default"}) {
+		t.Errorf("expected the last working credential first, found %v", ids)
+	}
+	if candidates[1].Password != "10.0.0.1" {
+		t.Errorf("expected the helper to get the bmc ip, found %q", candidates[1].Password)
+	}
+	if methods := []string{candidates[0].authMethod(), candidates[1].authMethod(), candidates[2].authMethod()}; !reflect.DeepEqual(methods, []string{model.AuthDefaultVendor, model.AuthPrimary, model.AuthFallback}) {
+		t.Errorf("expected the vendor default, then the primary and a fallback, found %v", methods)
+	}
+
+	viper.Set("collector.try_default_credentials", false)
+	candidates, err = p.Candidates(&CredentialQuery{IP: "10.0.0.1", Site: "ams4", Vendor: "Dell"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := credentialIDs(candidates); !reflect.DeepEqual(ids, []string{"vault-ams4", "default"}) {
+		t.Errorf("expected the vendor default credentials to be left out, found %v", ids)
+	}
+}
+
+type fakeBmc struct {
+	password string
+	tried    []string
+}
+
+func (f *fakeBmc) CheckCredentials() error {
+	if f.tried[len(f.tried)-1] != f.password {
+		return errors.ErrLoginFailed
+	}
+	return nil
+}
+
+func (f *fakeBmc) UpdateCredentials(username string, password string) {
+	f.tried = append(f.tried, password)
+}
+
+func (f *fakeBmc) Vendor() string {
+	return "Dell"
+}
+
+type staticProvider []*Credential
+
+func (s staticProvider) Candidates(q *CredentialQuery) ([]*Credential, error) {
+	return s, nil
+}
+
+func TestLogin(t *testing.T) {
+	p := staticProvider{{ID: "a", Password: "a", primary: true}, {ID: "b", Password: "b"}, {ID: "c", Password: "c", vendorDefault: true}}
+
+	bmc := &fakeBmc{password: "b"}
+	q := &CredentialQuery{IP: "10.0.0.1"}
+	id, authMethod, err := login(bmc, p, q)
+	if err != nil || id != "b" || authMethod != model.AuthFallback {
+		t.Errorf("expected to log in with b as a fallback, found %q %q %v", id, authMethod, err)
+	}
+	if !reflect.DeepEqual(bmc.tried, []string{"a", "b"}) || q.Vendor != "Dell" {
+		t.Errorf("expected a then b to be tried for a Dell, found %v %q", bmc.tried, q.Vendor)
+	}
+
+	for password, expected := range map[string]string{"a": model.AuthPrimary, "c": model.AuthDefaultVendor} {
+		if _, authMethod, _ := login(&fakeBmc{password: password}, p, &CredentialQuery{}); authMethod != expected {
+			t.Errorf("expected to log in with %s as %s, found %q", password, expected, authMethod)
+		}
+	}
+
+	if _, authMethod, err := login(&fakeBmc{password: "z"}, p, &CredentialQuery{}); err != ErrLoginFailed || authMethod != model.AuthFailed {
+		t.Errorf("expected the login to fail, found %q %v", authMethod, err)
+	}
+}
+
+func TestConfigProviderPassFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passFile := filepath.Join(dir, "bmc_pass")
+	if err := ioutil.WriteFile(passFile, []byte("Wololo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("bmc_user", "Priest")
+	viper.Set("bmc_pass_file", passFile)
+	defer func() {
+		viper.Set("bmc_user", nil)
+		viper.Set("bmc_pass_file", nil)
+	}()
+
+	candidates, err := configProvider{}.Candidates(&CredentialQuery{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Password != "Wololo" {
+		t.Errorf("expected the password of the file without its newline, found %+v", candidates)
+	}
+}
@@ -21,4 +21,6 @@ var (
 	ErrUnableToReadData = errors.New("unable to read data from this device")
 	// ErrVendorNotSupported is returned when we are able to identify a vendor but we won't support it
 	ErrVendorNotSupported = errors.New("vendor not supported")
+	// ErrNotBladeBmc is returned when the bmc address of a blade answers as something else than a blade bmc
+	ErrNotBladeBmc = errors.New("not a blade bmc")
 )
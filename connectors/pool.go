@@ -0,0 +1,160 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// defaultPool collects the hosts no pool claims, with collector.concurrency workers
+const defaultPool = "default"
+
+// collectionPool is a set of workers collecting the hosts whose discover
+// hint or http fingerprint matches one of its hints, with its own
+// concurrency and timeouts
+type collectionPool struct {
+	name        string
+	hints       []string
+	concurrency int
+	input       chan string
+	busy        int64
+}
+
+// timeoutKey returns the collector.pools.<name>.timeouts.<key> setting of
+// the pool, falling back to collector.timeouts.<key>
+func (p *collectionPool) timeoutKey(key string) string {
+	if p != nil && p.name != defaultPool {
+		if poolKey := fmt.Sprintf("collector.pools.%s.timeouts.%s", p.name, key); viper.IsSet(poolKey) {
+			return poolKey
+		}
+	}
+	return "collector.timeouts." + key
+}
+
+// working counts a worker of the pool taking a host, or done with it
+func (p *collectionPool) working(delta int64) {
+	busy := atomic.AddInt64(&p.busy, delta)
+	if viper.GetBool("metrics.enabled") {
+		metrics.UpdateGauge([]string{"collect.pool." + p.name + ".busy_workers"}, busy)
+		metrics.UpdateGauge([]string{"collect.pool." + p.name + ".queue_depth"}, int64(len(p.input)))
+	}
+}
+
+// collectionPools dispatches the hosts to the pools configured under
+// collector.pools and the default one
+type collectionPools struct {
+	pools  []*collectionPool
+	byHint map[string]*collectionPool
+}
+
+// newCollectionPools reads the pools from the config, a hint can only
+// belong to one of them
+func newCollectionPools() (*collectionPools, error) {
+	p := &collectionPools{byHint: make(map[string]*collectionPool)}
+
+	var names []string
+	for name := range viper.GetStringMap("collector.pools") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == defaultPool {
+			return nil, fmt.Errorf("collector.pools: %s is reserved for the hosts no pool claims", defaultPool)
+		}
+
+		concurrency := viper.GetInt(fmt.Sprintf("collector.pools.%s.concurrency", name))
+		if concurrency <= 0 {
+			return nil, fmt.Errorf("collector.pools.%s: concurrency must be positive", name)
+		}
+
+		pool := &collectionPool{
+			name:        name,
+			hints:       viper.GetStringSlice(fmt.Sprintf("collector.pools.%s.hints", name)),
+			concurrency: concurrency,
+			input:       make(chan string, concurrency),
+		}
+		for _, hint := range pool.hints {
+			if other, ok := p.byHint[hint]; ok {
+				return nil, fmt.Errorf("collector.pools.%s: hint %s already belongs to %s", name, hint, other.name)
+			}
+			p.byHint[hint] = pool
+		}
+		p.pools = append(p.pools, pool)
+	}
+
+	concurrency := viper.GetInt("collector.concurrency")
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("collector.concurrency must be positive")
+	}
+	p.pools = append(p.pools, &collectionPool{
+		name:        defaultPool,
+		concurrency: concurrency,
+		input:       make(chan string, concurrency),
+	})
+
+	return p, nil
+}
+
+// start runs the workers of every pool until their input is closed or stop
+// is done, wg is done once they are all over
+func (p *collectionPools) start(stop context.Context, abandon context.Context, source *string, db *gorm.DB, credentials CredentialProvider, wg *sync.WaitGroup) {
+	for _, pool := range p.pools {
+		log.WithFields(log.Fields{"operation": "collection", "pool": pool.name, "concurrency": pool.concurrency, "hints": pool.hints}).Debug("starting pool")
+		wg.Add(pool.concurrency)
+		for i := 0; i < pool.concurrency; i++ {
+			go func(pool *collectionPool) {
+				defer wg.Done()
+				collect(stop, abandon, pool, source, db, credentials)
+			}(pool)
+		}
+	}
+}
+
+// dispatch returns the pool collecting the host: the one of its discover
+// hint or, before any collection found it, of its http fingerprint
+func (p *collectionPools) dispatch(host string, db *gorm.DB) *collectionPool {
+	hint := hintFromDB(host, db)
+	if hint == "" {
+		fingerprint := model.HTTPFingerprint{}
+		if err := db.Where("ip = ? and hint <> ''", host).Order("updated_at desc").First(&fingerprint).Error; err == nil {
+			hint = fingerprint.Hint
+		}
+	}
+
+	if pool, ok := p.byHint[hint]; ok {
+		return pool
+	}
+	return p.pools[len(p.pools)-1]
+}
+
+// enqueue hands the host to its pool, it returns false once the collection
+// is draining and the host wasn't taken
+func (p *collectionPools) enqueue(stop context.Context, host string, db *gorm.DB) bool {
+	pool := p.dispatch(host, db)
+	select {
+	case pool.input <- host:
+		if viper.GetBool("metrics.enabled") {
+			metrics.UpdateGauge([]string{"collect.pool." + pool.name + ".queue_depth"}, int64(len(pool.input)))
+		}
+		return true
+	case <-stop.Done():
+		return false
+	}
+}
+
+// close the input of every pool, their workers finish what's queued
+func (p *collectionPools) close() {
+	for _, pool := range p.pools {
+		close(pool.input)
+	}
+}
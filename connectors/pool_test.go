@@ -0,0 +1,74 @@
+package connectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/discover"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestCollectionPools(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("collector.concurrency", 4)
+	viper.Set("collector.pools", map[string]interface{}{
+		"dell": map[string]interface{}{
+			"hints":       []string{discover.ProbeIdrac8, discover.ProbeIdrac9},
+			"concurrency": 2,
+			"timeouts":    map[string]interface{}{"collect": "15m"},
+		},
+	})
+	defer func() {
+		viper.Set("collector.concurrency", nil)
+		viper.Set("collector.pools", nil)
+	}()
+
+	pools, err := newCollectionPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools.pools) != 2 {
+		t.Fatalf("expected the dell and default pools, found %d", len(pools.pools))
+	}
+
+	db.Create(&model.DiscoverHint{IP: "10.0.0.1", Hint: discover.ProbeIdrac9})
+	db.Create(&model.HTTPFingerprint{ScannedPortID: "1", IP: "10.0.0.2", Hint: discover.ProbeIdrac8})
+	db.Create(&model.DiscoverHint{IP: "10.0.0.3", Hint: discover.ProbeSupermicrox})
+
+	for ip, expected := range map[string]string{
+		"10.0.0.1": "dell",
+		"10.0.0.2": "dell",
+		"10.0.0.3": defaultPool,
+		"10.0.0.4": defaultPool,
+	} {
+		if pool := pools.dispatch(ip, db); pool.name != expected {
+			t.Errorf("%s: expected the %s pool, found %s", ip, expected, pool.name)
+		}
+	}
+
+	dell := pools.dispatch("10.0.0.1", db)
+	if key := dell.timeoutKey("collect"); viper.GetDuration(key) != 15*time.Minute {
+		t.Errorf("expected the collect timeout of the dell pool, found %s", key)
+	}
+	if key := dell.timeoutKey("host"); key != "collector.timeouts.host" {
+		t.Errorf("expected the host timeout to fall back to the collector one, found %s", key)
+	}
+
+	viper.Set("collector.pools", map[string]interface{}{
+		"dell":  map[string]interface{}{"hints": []string{discover.ProbeIdrac9}, "concurrency": 2},
+		"idrac": map[string]interface{}{"hints": []string{discover.ProbeIdrac9}, "concurrency": 2},
+	})
+	if _, err := newCollectionPools(); err == nil {
+		t.Errorf("expected a hint shared by two pools to be refused")
+	}
+
+	viper.Set("collector.pools", nil)
+	viper.Set("collector.concurrency", 0)
+	if _, err := newCollectionPools(); err == nil {
+		t.Errorf("expected a default pool without workers to be refused")
+	}
+}
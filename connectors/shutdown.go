@@ -0,0 +1,195 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// drainOnSignal returns a context canceled on SIGINT or SIGTERM, to stop
+// taking new hosts, and one canceled collector.shutdown_grace later or on a
+// second signal, to abandon the hosts still being collected
+func drainOnSignal() (stop context.Context, abandon context.Context, cancel func()) {
+	stop, stopFn := context.WithCancel(context.Background())
+	abandon, abandonFn := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			grace := viper.GetDuration("collector.shutdown_grace")
+			log.WithFields(log.Fields{"operation": "shutdown", "grace": grace}).Warnf("received %s, draining", sig)
+			stopFn()
+			select {
+			case <-time.After(grace):
+				log.WithFields(log.Fields{"operation": "shutdown"}).Warn("grace period over, abandoning the hosts being collected")
+			case sig := <-signals:
+				log.WithFields(log.Fields{"operation": "shutdown"}).Warnf("received %s, abandoning the hosts being collected", sig)
+			case <-abandon.Done():
+			}
+			abandonFn()
+		case <-abandon.Done():
+		}
+	}()
+
+	return stop, abandon, func() {
+		signal.Stop(signals)
+		stopFn()
+		abandonFn()
+	}
+}
+
+// withTimeout bounds the context with the duration of the config key, 0
+// being no bound
+func withTimeout(ctx context.Context, key string) (context.Context, context.CancelFunc) {
+	if timeout := viper.GetDuration(key); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// sessionCloseWait is how long closing a session waits for a stage still
+// using it, past it the session is closed as soon as the stage returns
+const sessionCloseWait = 5 * time.Second
+
+// pending counts the stages abandoned on a deadline and the sessions waiting
+// for them to be closed, so exiting can give them a chance to finish
+var pending sync.WaitGroup
+
+// session tracks the stages run against the bmc of a host: bmclib can't be
+// interrupted, so a stage abandoned on a deadline keeps using the bmc and
+// what it opens late must still be closed
+type session struct {
+	stages sync.WaitGroup
+}
+
+// runStage runs fn within the stage deadline of the pool and the one of the
+// host, given to fn as its context. On a deadline fn is abandoned and its
+// results must be left alone, abandoned is then called once fn returns to
+// release what it opened
+func (s *session) runStage(ctx context.Context, pool *collectionPool, stage string, fn func(ctx context.Context) error, abandoned func()) error {
+	ctx, cancel := withTimeout(ctx, pool.timeoutKey(stage))
+	defer cancel()
+
+	var mu sync.Mutex
+	var finished, late bool
+
+	done := make(chan error, 1)
+	s.stages.Add(1)
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		defer s.stages.Done()
+
+		err := fn(ctx)
+		mu.Lock()
+		finished = true
+		wasLate := late
+		mu.Unlock()
+		if wasLate && abandoned != nil {
+			abandoned()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		mu.Lock()
+		defer mu.Unlock()
+		if finished {
+			return <-done
+		}
+		late = true
+		return fmt.Errorf("%s stage interrupted: %w", stage, ctx.Err())
+	}
+}
+
+// close runs closeFn once no stage uses the session anymore, without
+// waiting more than sessionCloseWait for an abandoned one
+func (s *session) close(closeFn func()) {
+	idle := make(chan struct{})
+	go func() {
+		s.stages.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		closeFn()
+	case <-time.After(sessionCloseWait):
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			<-idle
+			closeFn()
+		}()
+	}
+}
+
+// runStage runs a stage that closes whatever it opens by itself
+func runStage(ctx context.Context, pool *collectionPool, stage string, fn func(ctx context.Context) error) error {
+	return (&session{}).runStage(ctx, pool, stage, fn, nil)
+}
+
+// closeConn closes the connection to a bmc or a chassis
+func closeConn(conn interface{}) {
+	var err error
+	switch c := conn.(type) {
+	case devices.Bmc:
+		err = c.Close(context.Background())
+	case devices.Cmc:
+		err = c.Close()
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "closing connection"}).Debug(err)
+	}
+}
+
+// waitPending gives the abandoned stages and the sessions being closed up to
+// collector.shutdown_grace to finish
+func waitPending() {
+	idle := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+	case <-time.After(viper.GetDuration("collector.shutdown_grace")):
+		log.WithFields(log.Fields{"operation": "shutdown"}).Warn("exiting with bmc sessions still open")
+	}
+}
+
+// interrupted tells whether the stage was abandoned on a deadline or a
+// cancellation, its results are then still being written
+func interrupted(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// stageFailure classifies the error of a stage: a deadline is a timeout, a
+// cancellation is the collector shutting down and anything else is failure
+func stageFailure(err error, failure string) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return model.FailureTimeout
+	case errors.Is(err, context.Canceled):
+		return model.FailureCanceled
+	}
+	return failure
+}
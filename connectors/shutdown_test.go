@@ -0,0 +1,95 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestRunStage(t *testing.T) {
+	viper.Set("collector.timeouts.login", 10*time.Millisecond)
+	defer viper.Set("collector.timeouts.login", nil)
+
+	expected := errors.New("refused")
+	err := runStage(context.Background(), nil, model.CollectionStageLogin, func(context.Context) error { return expected })
+	if err != expected {
+		t.Errorf("expected the error of the stage, found %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	err = runStage(context.Background(), nil, model.CollectionStageLogin, func(context.Context) error {
+		<-release
+		return nil
+	})
+	if failure := stageFailure(err, model.FailureConnection); failure != model.FailureTimeout {
+		t.Errorf("expected a stage past its deadline to be a timeout, found %q %v", failure, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = runStage(ctx, nil, model.CollectionStageCollect, func(context.Context) error {
+		<-release
+		return nil
+	})
+	if failure := stageFailure(err, model.FailureCollection); failure != model.FailureCanceled {
+		t.Errorf("expected an abandoned stage to be canceled, found %q %v", failure, err)
+	}
+
+	if failure := stageFailure(expected, model.FailureConnection); failure != model.FailureConnection {
+		t.Errorf("expected other errors to keep their failure, found %q", failure)
+	}
+}
+
+func TestSessionClosesAbandonedConnections(t *testing.T) {
+	viper.Set("collector.timeouts.scan", 10*time.Millisecond)
+	defer viper.Set("collector.timeouts.scan", nil)
+
+	type fakeConn struct{ closed bool }
+
+	sess := &session{}
+	release := make(chan struct{})
+	closed := make(chan *fakeConn, 1)
+	var conn *fakeConn
+	err := sess.runStage(context.Background(), nil, model.CollectionStageScan, func(context.Context) error {
+		<-release
+		// the bmc answers past the deadline
+		conn = &fakeConn{}
+		return nil
+	}, func() {
+		conn.closed = true
+		closed <- conn
+	})
+	if !interrupted(err) {
+		t.Fatalf("expected the scan to be interrupted, found %v", err)
+	}
+
+	sessionClosed := make(chan struct{})
+	go sess.close(func() { close(sessionClosed) })
+
+	select {
+	case <-sessionClosed:
+		t.Fatalf("expected the session to wait for the abandoned stage before closing")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case c := <-closed:
+		if !c.closed {
+			t.Errorf("expected the late connection to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the late connection to be closed")
+	}
+	select {
+	case <-sessionClosed:
+	case <-time.After(time.Second):
+		t.Errorf("expected the session to be closed once the stage returned")
+	}
+}
@@ -94,23 +94,11 @@ func (f *Filters) BuildQuery(m interface{}, db *gorm.DB) (q *gorm.DB, err error)
 			if len(values) == 1 && values[0] == "" {
 				continue
 			}
-			rfct := reflect.ValueOf(m)
-			rfctType := rfct.Type()
-
-			var structJSONMemberName string
-			for i := 0; i < rfctType.NumField(); i++ {
-				jsondName := rfctType.Field(i).Tag.Get("json")
-				if key == jsondName {
-					structJSONMemberName = jsondName
-					break
-				}
-			}
-
-			if structJSONMemberName == "" || structJSONMemberName == "-" {
+			if jsonField(reflect.TypeOf(m), key) == -1 {
 				return q, err
 			}
 
-			op := operation(structJSONMemberName, filter.Operator)
+			op := operation(key, filter.Operator)
 			if op == "" {
 				return nil, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid filter operation: %s", filter.Operator), 400)
 			}
@@ -127,6 +115,20 @@ func (f *Filters) BuildQuery(m interface{}, db *gorm.DB) (q *gorm.DB, err error)
 	return q, err
 }
 
+// jsonField returns the index of the field of the struct whose json name is
+// key, or -1 when there is none
+func jsonField(t reflect.Type, key string) int {
+	if key == "" || key == "-" {
+		return -1
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("json") == key {
+			return i
+		}
+	}
+	return -1
+}
+
 // Clean cleanup the current filter list
 func (f *Filters) Clean() {
 	f.filters = make([]*Filter, 0)
@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/manyminds/api2go"
+)
+
+// Match tells whether a value that isn't stored passes the filters, the
+// fields and operations are the ones BuildQuery accepts for a query
+func (f *Filters) Match(m interface{}) (bool, error) {
+	rv := reflect.ValueOf(m)
+	for _, filter := range f.Get() {
+		for key, values := range filter.Filter {
+			if len(values) == 1 && values[0] == "" {
+				continue
+			}
+
+			i := jsonField(rv.Type(), key)
+			if i == -1 {
+				continue
+			}
+			if operation(key, filter.Operator) == "" {
+				return false, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid filter operation: %s", filter.Operator), 400)
+			}
+
+			ok, err := matchField(filter.Operator, rv.Field(i), values)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// matchField compares the field with the values of the filter, a nil
+// pointer being an empty value
+func matchField(operator string, field reflect.Value, values []string) (bool, error) {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return matchValue(operator, "", false, values)
+		}
+		field = field.Elem()
+	}
+
+	if t, isTime := field.Interface().(time.Time); isTime {
+		return matchTime(operator, t, values)
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return matchValue(operator, fmt.Sprint(field.Interface()), true, values)
+	}
+	return matchValue(operator, fmt.Sprint(field.Interface()), false, values)
+}
+
+func matchValue(operator string, value string, numeric bool, values []string) (bool, error) {
+	if operator == "eq" || operator == "ne" {
+		found := false
+		for _, v := range values {
+			if v == value {
+				found = true
+			}
+		}
+		return found == (operator == "eq"), nil
+	}
+
+	for _, v := range values {
+		cmp := 0
+		if numeric {
+			a, _ := strconv.Atoi(value)
+			b, err := strconv.Atoi(v)
+			if err != nil {
+				return false, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid filter value: %s", v), 400)
+			}
+			cmp = a - b
+		} else if value < v {
+			cmp = -1
+		} else if value > v {
+			cmp = 1
+		}
+		if !compared(operator, cmp) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchTime compares the time with values given as RFC3339 times or dates,
+// whatever the location they are written in
+func matchTime(operator string, value time.Time, values []string) (bool, error) {
+	for _, v := range values {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", v); err != nil {
+				return false, api2go.NewHTTPError(nil, fmt.Sprintf("Invalid filter value: %s", v), 400)
+			}
+		}
+
+		cmp := 0
+		if value.Before(t) {
+			cmp = -1
+		} else if value.After(t) {
+			cmp = 1
+		}
+
+		switch {
+		case operator == "eq" && cmp == 0:
+			return true, nil
+		case operator != "eq" && !compared(operator, cmp):
+			return false, nil
+		}
+	}
+	return operator != "eq", nil
+}
+
+// compared tells whether the result of a comparison satisfies the operation
+func compared(operator string, cmp int) bool {
+	switch operator {
+	case "ne":
+		return cmp != 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	scannedAt := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	value := struct {
+		CIDR       string     `json:"cidr"`
+		Hosts      int        `json:"hosts"`
+		LastScanAt *time.Time `json:"last_scan_at"`
+		Never      *time.Time `json:"never"`
+		Hidden     string     `json:"-"`
+	}{CIDR: "10.0.0.0/24", Hosts: 12, LastScanAt: &scannedAt}
+
+	tt := []struct {
+		name     string
+		key      string
+		values   []string
+		operator string
+		match    bool
+		err      bool
+	}{
+		{"equal", "cidr", []string{"10.0.1.0/24", "10.0.0.0/24"}, "eq", true, false},
+		{"not equal", "cidr", []string{"10.0.0.0/24"}, "ne", false, false},
+		{"numbers", "hosts", []string{"9"}, "gt", true, false},
+		{"numbers aren't strings", "hosts", []string{"100"}, "lt", true, false},
+		{"invalid number", "hosts", []string{"many"}, "lt", false, true},
+		{"date", "last_scan_at", []string{"2021-03-05"}, "lt", true, false},
+		{"time", "last_scan_at", []string{"2021-03-04T12:00:00+02:00"}, "eq", true, false},
+		{"invalid time", "last_scan_at", []string{"yesterday"}, "lt", false, true},
+		{"nil", "never", []string{"2021-03-05"}, "eq", false, false},
+		{"unknown field", "site", []string{"ams4"}, "eq", true, false},
+		{"hidden field", "-", []string{"x"}, "eq", true, false},
+		{"invalid operation", "cidr", []string{"10.0.0.0/24"}, "like", false, true},
+	}
+
+	for _, tc := range tt {
+		filters := &Filters{}
+		filters.Add(tc.key, tc.values, tc.operator)
+		match, err := filters.Match(value)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if match != tc.match {
+			t.Errorf("%s: expected match to be %t", tc.name, tc.match)
+		}
+	}
+}
@@ -53,6 +53,7 @@ type Stats struct {
 	Psu          UnitStats `json:"psus"`
 	Disk         UnitStats `json:"disks"`
 	Fan          UnitStats `json:"fans"`
+	DiscoverHint UnitStats `json:"discover_hints"`
 }
 
 // UpdateUptime updates uptime based on StartTime
@@ -70,7 +71,8 @@ func (s *Stats) GatherDBStats(
 	scannedPortStorage *storage.ScannedPortStorage,
 	psuStorage *storage.PsuStorage,
 	diskStorage *storage.DiskStorage,
-	fanStorage *storage.FanStorage) {
+	fanStorage *storage.FanStorage,
+	discoverHintStorage *storage.DiscoverHintStorage) {
 	names := []string{
 		"chassis",
 		"blades",
@@ -81,6 +83,7 @@ func (s *Stats) GatherDBStats(
 		"psus",
 		"disks",
 		"fans",
+		"discover_hints",
 	}
 
 	for i, r := range []countable{
@@ -93,6 +96,7 @@ func (s *Stats) GatherDBStats(
 		psuStorage,
 		diskStorage,
 		fanStorage,
+		discoverHintStorage,
 	} {
 		u := &UnitStats{}
 		switch i {
@@ -114,6 +118,8 @@ func (s *Stats) GatherDBStats(
 			u = &s.Disk
 		case 8:
 			u = &s.Fan
+		case 9:
+			u = &s.DiscoverHint
 		}
 		if u.Vendors == nil {
 			u.Vendors = map[string]Asset{}
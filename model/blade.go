@@ -72,6 +72,14 @@ func NewBladeFromDevice(b *devices.Blade) (blade *Blade) {
 	return blade
 }
 
+// Enrichment statuses of a Blade, what its own bmc told on top of the chassis
+const (
+	BladeEnriched = "enriched"
+	BladeSkipped  = "skipped"
+	BladeFailed   = "failed"
+	BladeTimedOut = "timed_out"
+)
+
 // Blade contains all the blade information we will expose across different vendors
 type Blade struct {
 	Serial               string       `json:"serial" gorm:"primary_key"`
@@ -86,6 +94,10 @@ type Blade struct {
 	BmcLicenceType       string       `json:"bmc_licence_type"`
 	BmcLicenceStatus     string       `json:"bmc_licence_status"`
 	BmcAuth              bool         `json:"bmc_auth"`
+	CredentialID         string       `json:"credential_id"`
+	AuthMethod           string       `json:"auth_method"`
+	EnrichmentStatus     string       `json:"enrichment_status"`
+	EnrichmentError      string       `gorm:"type:text" json:"enrichment_error"`
 	Disks                []*Disk      `json:"-" gorm:"ForeignKey:BladeSerial"`
 	Nics                 []*Nic       `json:"-" gorm:"ForeignKey:BladeSerial"`
 	BladePosition        int          `json:"blade_position"`
@@ -192,7 +204,7 @@ func (b *Blade) Diff(blade *Blade) (differences []string) {
 	sort.Sort(byDiskSerial(blade.Disks))
 
 	for _, diff := range pretty.Diff(b, blade) {
-		if !strings.Contains(diff, "UpdatedAt.") && !strings.Contains(diff, "PowerKw") && !strings.Contains(diff, "TempC") {
+		if !strings.Contains(diff, "UpdatedAt.") && !strings.Contains(diff, "PowerKw") && !strings.Contains(diff, "TempC") && !strings.Contains(diff, "EnrichmentError") {
 			differences = append(differences, diff)
 		}
 	}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Certificate is the leaf tls certificate presented by the open https
+// ScannedPort, it's linked to the chassis, blade or discrete whose
+// bmc_address matches its ip once they have been collected
+type Certificate struct {
+	ScannedPortID     string    `gorm:"primary_key" json:"-"`
+	IP                string    `json:"ip"`
+	Port              int       `json:"port"`
+	Subject           string    `json:"subject"`
+	Sans              string    `json:"sans"`
+	Issuer            string    `json:"issuer"`
+	Serial            string    `json:"serial"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	KeyType           string    `json:"key_type"`
+	KeySize           int       `json:"key_size"`
+	SelfSigned        bool      `json:"self_signed"`
+	FingerprintSha256 string    `json:"fingerprint_sha256"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	ChassisSerial  string `gorm:"-" json:"-"`
+	BladeSerial    string `gorm:"-" json:"-"`
+	DiscreteSerial string `gorm:"-" json:"-"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (c Certificate) GetName() string {
+	return "certificates"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (c Certificate) GetID() string {
+	return c.ScannedPortID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (c Certificate) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+		{
+			Type:         "chassis",
+			Name:         "chassis",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+		{
+			Type:         "blades",
+			Name:         "blades",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+		{
+			Type:         "discretes",
+			Name:         "discretes",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (c Certificate) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{
+		{
+			ID:           c.ScannedPortID,
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+
+	if c.ChassisSerial != "" {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           c.ChassisSerial,
+			Type:         "chassis",
+			Name:         "chassis",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	if c.BladeSerial != "" {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           c.BladeSerial,
+			Type:         "blades",
+			Name:         "blades",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	if c.DiscreteSerial != "" {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           c.DiscreteSerial,
+			Type:         "discretes",
+			Name:         "discretes",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	return result
+}
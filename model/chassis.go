@@ -109,6 +109,8 @@ type Chassis struct {
 	BmcSSHReachable   bool            `json:"bmc_ssh_reachable"`
 	BmcWEBReachable   bool            `json:"bmc_web_reachable"`
 	BmcAuth           bool            `json:"bmc_auth"`
+	CredentialID      string          `json:"credential_id"`
+	AuthMethod        string          `json:"auth_method"`
 	Blades            []*Blade        `json:"-" gorm:"ForeignKey:ChassisSerial"`
 	FaultySlots       pq.Int64Array   `json:"faulty_slots" gorm:"type:integer[2]"`
 	StorageBlades     []*StorageBlade `json:"-" gorm:"ForeignKey:ChassisSerial"`
@@ -239,7 +241,7 @@ func (c *Chassis) Diff(chassis *Chassis) (differences []string) {
 	sort.Sort(byMacAddress(chassis.Nics))
 
 	for _, diff := range pretty.Diff(c, chassis) {
-		if !strings.Contains(diff, "UpdatedAt.") && !strings.Contains(diff, "PowerKw") && !strings.Contains(diff, "TempC") {
+		if !strings.Contains(diff, "UpdatedAt.") && !strings.Contains(diff, "PowerKw") && !strings.Contains(diff, "TempC") && !strings.Contains(diff, "EnrichmentError") {
 			differences = append(differences, diff)
 		}
 	}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// States of a CircuitBreaker
+const (
+	// BreakerClosed is a bmc that failed but is still collected
+	BreakerClosed = "closed"
+	// BreakerOpen is a bmc that isn't collected until open_until
+	BreakerOpen = "open"
+	// BreakerDeadLetter is a bmc that isn't collected until it's reset
+	BreakerDeadLetter = "dead_letter"
+)
+
+// CircuitBreaker counts the consecutive failed collections of a bmc to stop
+// collecting it for a while and eventually for good. A successful collection
+// removes it
+type CircuitBreaker struct {
+	IP          string     `gorm:"primary_key" json:"ip"`
+	State       string     `json:"state"`
+	Failures    int        `json:"failures"`
+	LastFailure string     `json:"last_failure"`
+	LastError   string     `gorm:"type:text" json:"last_error"`
+	OpenUntil   *time.Time `json:"open_until"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (c CircuitBreaker) GetName() string {
+	return "circuit_breakers"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (c CircuitBreaker) GetID() string {
+	return c.IP
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Stages of a CollectionAttempt, in the order they are reached
+const (
+	CollectionStageScan    = "scan"
+	CollectionStageLogin   = "login"
+	CollectionStageDetect  = "detect"
+	CollectionStageCollect = "collect"
+	CollectionStageEnrich  = "enrich"
+	CollectionStageDone    = "done"
+)
+
+// Results of a CollectionAttempt
+const (
+	CollectionSucceeded = "succeeded"
+	CollectionFailed    = "failed"
+	CollectionSkipped   = "skipped"
+)
+
+// Classified failures of a CollectionAttempt
+const (
+	FailureScan             = "scan_failed"
+	FailureWrongCredentials = "wrong_credentials"
+	FailureConnection       = "connection_failed"
+	FailureBladeDetection   = "blade_detection_failed"
+	FailureChassisSerial    = "chassis_serial_failed"
+	FailureCollection       = "collection_failed"
+	FailureUnknownDevice    = "unknown_device"
+	// FailureTimeout is a stage that went past its deadline or the one of the host
+	FailureTimeout = "timeout"
+	// FailureCanceled is a collection abandoned by the collector shutting down
+	FailureCanceled = "canceled"
+	// FailureManagedBlade is a blade skipped since its chassis collects it
+	FailureManagedBlade = "managed_by_chassis"
+)
+
+// CollectionAttempt is the outcome of the collection of a bmc by the cli or
+// a worker: how far it went, why it failed and which asset it collected
+type CollectionAttempt struct {
+	ID             string    `gorm:"primary_key" json:"-"`
+	IP             string    `gorm:"index" json:"ip"`
+	Worker         string    `json:"worker"`
+	Source         string    `json:"source"`
+	Pool           string    `json:"pool"`
+	Stage          string    `json:"stage"`
+	Result         string    `json:"result"`
+	Failure        string    `json:"failure"`
+	Error          string    `gorm:"type:text" json:"error"`
+	AssetType      string    `json:"asset_type"`
+	AssetSerial    string    `gorm:"index" json:"asset_serial"`
+	BladesEnriched string    `json:"blades_enriched"`
+	BladesSkipped  string    `json:"blades_skipped"`
+	BladesFailed   string    `json:"blades_failed"`
+	BladesTimedOut string    `json:"blades_timed_out"`
+	StartedAt      time.Time `gorm:"index" json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// GenID generates the ID based on the date we have
+func (c *CollectionAttempt) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", c.IP, c.Worker, c.StartedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (c *CollectionAttempt) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", c.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (c CollectionAttempt) GetName() string {
+	return "collection_attempts"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (c CollectionAttempt) GetID() string {
+	return c.ID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (c CollectionAttempt) GetReferences() []jsonapi.Reference {
+	var references []jsonapi.Reference
+	for _, name := range []string{"chassis", "blades", "discretes"} {
+		references = append(references, jsonapi.Reference{
+			Type:         name,
+			Name:         name,
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+	return references
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (c CollectionAttempt) GetReferencedIDs() []jsonapi.ReferenceID {
+	if c.AssetType == "" || c.AssetSerial == "" {
+		return []jsonapi.ReferenceID{}
+	}
+
+	return []jsonapi.ReferenceID{
+		{
+			ID:           c.AssetSerial,
+			Type:         c.AssetType,
+			Name:         c.AssetType,
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
@@ -2,8 +2,19 @@ package model
 
 import "time"
 
+// DiscoverHint stores the bmclib probe that identified the device behind an ip
 type DiscoverHint struct {
-	IP        string `gorm:"primary_key;column:ip"`
-	Hint      string `gorm:"column:hint"`
-	UpdatedAt time.Time
+	IP        string    `gorm:"primary_key;column:ip" json:"ip"`
+	Hint      string    `gorm:"column:hint" json:"hint"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (d DiscoverHint) GetName() string {
+	return "discover_hints"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (d DiscoverHint) GetID() string {
+	return d.IP
 }
@@ -95,6 +95,8 @@ type Discrete struct {
 	BmcLicenceType       string    `json:"bmc_licence_type"`
 	BmcLicenceStatus     string    `json:"bmc_licence_status"`
 	BmcAuth              bool      `json:"bmc_auth"`
+	CredentialID         string    `json:"credential_id"`
+	AuthMethod           string    `json:"auth_method"`
 	Disks                []*Disk   `json:"-" gorm:"ForeignKey:BladeSerial"`
 	Nics                 []*Nic    `json:"-" gorm:"ForeignKey:DiscreteSerial"`
 	Psus                 []*Psu    `json:"-" gorm:"ForeignKey:DiscreteSerial"`
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Host is an address leased by the dhcp server, with the hardware address
+// and hostname of the lease, it ties the mac of a bmc to its ip
+type Host struct {
+	IP          string    `gorm:"primary_key;column:ip" json:"ip"`
+	Site        string    `gorm:"index" json:"site"`
+	CIDR        string    `gorm:"column:cidr" json:"cidr"`
+	HwAddress   string    `gorm:"index" json:"hw_address"`
+	Hostname    string    `json:"hostname"`
+	LeaseExpire time.Time `json:"lease_expire"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (h Host) GetName() string {
+	return "hosts"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (h Host) GetID() string {
+	return h.IP
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// HTTPFingerprint contains what the web interface of a BMC tells about the
+// device to an unauthenticated client, it belongs to the open https ScannedPort
+type HTTPFingerprint struct {
+	ScannedPortID  string    `gorm:"primary_key" json:"-"`
+	IP             string    `json:"ip"`
+	Port           int       `json:"port"`
+	Server         string    `json:"server"`
+	Title          string    `json:"title"`
+	RedfishVendor  string    `json:"redfish_vendor"`
+	RedfishProduct string    `json:"redfish_product"`
+	RedfishVersion string    `json:"redfish_version"`
+	Hint           string    `json:"hint"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (h HTTPFingerprint) GetName() string {
+	return "http_fingerprints"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (h HTTPFingerprint) GetID() string {
+	return h.ScannedPortID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (h HTTPFingerprint) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (h HTTPFingerprint) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{
+		{
+			ID:           h.ScannedPortID,
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
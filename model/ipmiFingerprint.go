@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// IpmiFingerprint contains what a BMC tells about its IPMI implementation to
+// an unauthenticated client, it belongs to the open ipmi ScannedPort
+type IpmiFingerprint struct {
+	ScannedPortID    string    `gorm:"primary_key" json:"-"`
+	IP               string    `json:"ip"`
+	Port             int       `json:"port"`
+	IanaEnterprise   int       `json:"iana_enterprise"`
+	IpmiSupported    bool      `json:"ipmi_supported"`
+	IpmiVersion      string    `json:"ipmi_version"`
+	AuthTypes        string    `json:"auth_types"`
+	AnonymousLogin   bool      `json:"anonymous_login"`
+	NullUsernames    bool      `json:"null_usernames"`
+	NonNullUsernames bool      `json:"non_null_usernames"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (i IpmiFingerprint) GetName() string {
+	return "ipmi_fingerprints"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (i IpmiFingerprint) GetID() string {
+	return i.ScannedPortID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (i IpmiFingerprint) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (i IpmiFingerprint) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{
+		{
+			ID:           i.ScannedPortID,
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
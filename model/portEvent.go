@@ -0,0 +1,75 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// PortEvent is a change of state of a ScannedPort between two scans, e.g. a
+// bmc whose 443 went from open to closed
+type PortEvent struct {
+	ID            string    `gorm:"primary_key" json:"-"`
+	ScannedPortID string    `gorm:"index" json:"scanned_port_id"`
+	Site          string    `json:"site"`
+	CIDR          string    `gorm:"column:cidr" json:"cidr"`
+	IP            string    `gorm:"index" json:"ip"`
+	Port          int       `json:"port"`
+	Protocol      string    `json:"protocol"`
+	ScannedBy     string    `json:"scanned_by"`
+	OldState      string    `json:"old_state"`
+	NewState      string    `json:"new_state"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// GenID generates the ID based on the date we have
+func (p *PortEvent) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%d", p.ScannedPortID, p.ChangedAt.UnixNano()))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (p *PortEvent) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", p.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (p PortEvent) GetName() string {
+	return "port_events"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (p PortEvent) GetID() string {
+	return p.ID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (p PortEvent) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (p PortEvent) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{
+		{
+			ID:           p.ScannedPortID,
+			Type:         "scanned_ports",
+			Name:         "scanned_ports",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
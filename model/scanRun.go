@@ -0,0 +1,96 @@
+package model
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// States of a ScanRun and its ScanRunSubnets
+const (
+	ScanRunPending  = "pending"
+	ScanRunRunning  = "running"
+	ScanRunFinished = "finished"
+	ScanRunFailed   = "failed"
+)
+
+// ScanRun is a scan of one or more subnets as requested by the cli, the api
+// or a message received by the worker, the counters are the totals of its
+// ScanRunSubnets
+type ScanRun struct {
+	ID          string                  `gorm:"primary_key" json:"-"`
+	TriggeredBy string                  `json:"triggered_by"`
+	ScannedBy   string                  `json:"scanned_by"`
+	State       string                  `json:"state"`
+	Subnets     int                     `json:"subnets"`
+	HostsProbed int                     `json:"hosts_probed"`
+	OpenPorts   int                     `json:"open_ports"`
+	Errors      int                     `json:"errors"`
+	Error       string                  `gorm:"type:text" json:"error"`
+	StartedAt   time.Time               `json:"started_at"`
+	FinishedAt  *time.Time              `json:"finished_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	Profiles    map[string]*ProfileStat `gorm:"-" json:"profiles"`
+
+	ScanRunSubnets []*ScanRunSubnet `json:"-" gorm:"ForeignKey:ScanRunID"`
+}
+
+// ProfileStat are the counters of a ScanRun for one of the scan profiles
+type ProfileStat struct {
+	HostsProbed int `json:"hosts_probed"`
+	OpenPorts   int `json:"open_ports"`
+}
+
+// NewScanRunID returns a random id for a new ScanRun
+func NewScanRunID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// GetName to satisfy jsonapi naming schema
+func (s ScanRun) GetName() string {
+	return "scan_runs"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (s ScanRun) GetID() string {
+	return s.ID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s ScanRun) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scan_run_subnets",
+			Name:         "scan_run_subnets",
+			Relationship: jsonapi.ToManyRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (s ScanRun) GetReferencedIDs() []jsonapi.ReferenceID {
+	var result []jsonapi.ReferenceID
+
+	for _, subnet := range s.ScanRunSubnets {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           subnet.GetID(),
+			Type:         "scan_run_subnets",
+			Name:         "scan_run_subnets",
+			Relationship: jsonapi.ToManyRelationship,
+		})
+	}
+
+	return result
+}
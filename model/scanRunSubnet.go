@@ -0,0 +1,80 @@
+package model
+
+import (
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// ScanRunSubnet is the scan of one subnet within a ScanRun, it's created as
+// pending with the run and updated by whoever scans the subnet
+type ScanRunSubnet struct {
+	ID          string     `gorm:"primary_key" json:"-"`
+	ScanRunID   string     `gorm:"index" json:"scan_run_id"`
+	CIDR        string     `gorm:"column:cidr;index" json:"cidr"`
+	Site        string     `json:"site"`
+	Profile     string     `json:"profile"`
+	AdHoc       bool       `json:"ad_hoc"`
+	ScannedBy   string     `json:"scanned_by"`
+	State       string     `json:"state"`
+	HostsProbed int        `json:"hosts_probed"`
+	HostsAlive  int        `json:"hosts_alive"`
+	OpenPorts   int        `json:"open_ports"`
+	Errors      int        `json:"errors"`
+	Error       string     `gorm:"type:text" json:"error"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// GenID generates the ID based on the date we have
+func (s *ScanRunSubnet) GenID() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s", s.ScanRunID, s.CIDR))))
+}
+
+// BeforeCreate run all operations before creating the object
+func (s *ScanRunSubnet) BeforeCreate(scope *gorm.Scope) (err error) {
+	return scope.SetColumn("ID", s.GenID())
+}
+
+// GetName to satisfy jsonapi naming schema
+func (s ScanRunSubnet) GetName() string {
+	return "scan_run_subnets"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (s ScanRunSubnet) GetID() string {
+	return s.ID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s ScanRunSubnet) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "scan_runs",
+			Name:         "scan_runs",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (s ScanRunSubnet) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{
+		{
+			ID:           s.ScanRunID,
+			Type:         "scan_runs",
+			Name:         "scan_runs",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
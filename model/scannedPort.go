@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go/jsonapi"
 )
 
 /* READ THIS BEFORE CHANGING THE SCHEMA
@@ -24,7 +25,13 @@ type ScannedPort struct {
 	Protocol  string    `gorm:"unique_index:scanned_result" json:"protocol"`
 	ScannedBy string    `gorm:"unique_index:scanned_result" json:"scanned_by"`
 	State     string    `json:"state"`
+	Details   string    `json:"details"`
+	Stale     bool      `json:"stale"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	IpmiFingerprint *IpmiFingerprint `json:"-" gorm:"ForeignKey:ScannedPortID"`
+	HTTPFingerprint *HTTPFingerprint `json:"-" gorm:"ForeignKey:ScannedPortID"`
+	Certificate     *Certificate     `json:"-" gorm:"ForeignKey:ScannedPortID"`
 }
 
 // GenID generates the ID based on the date we have
@@ -46,3 +53,58 @@ func (s ScannedPort) GetName() string {
 func (s ScannedPort) GetID() string {
 	return s.ID
 }
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s ScannedPort) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{
+			Type:         "ipmi_fingerprints",
+			Name:         "ipmi_fingerprints",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+		{
+			Type:         "http_fingerprints",
+			Name:         "http_fingerprints",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+		{
+			Type:         "certificates",
+			Name:         "certificates",
+			Relationship: jsonapi.ToOneRelationship,
+		},
+	}
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (s ScannedPort) GetReferencedIDs() []jsonapi.ReferenceID {
+	var result []jsonapi.ReferenceID
+
+	if s.IpmiFingerprint != nil {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           s.IpmiFingerprint.GetID(),
+			Type:         "ipmi_fingerprints",
+			Name:         "ipmi_fingerprints",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	if s.HTTPFingerprint != nil {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           s.HTTPFingerprint.GetID(),
+			Type:         "http_fingerprints",
+			Name:         "http_fingerprints",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	if s.Certificate != nil {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           s.Certificate.GetID(),
+			Type:         "certificates",
+			Name:         "certificates",
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+
+	return result
+}
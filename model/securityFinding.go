@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// The auth_method of a chassis, blade or discrete tells which of its candidate
+// credentials the collector logged in with
+const (
+	// AuthPrimary is the first candidate credential of the bmc
+	AuthPrimary = "primary"
+	// AuthFallback is any later candidate but the vendor defaults
+	AuthFallback = "fallback"
+	// AuthDefaultVendor is the collector.default.<vendor> credential
+	AuthDefaultVendor = "default-vendor"
+	// AuthFailed is set when none of the candidates were accepted
+	AuthFailed = "failed"
+)
+
+// The types of SecurityFinding
+const (
+	// FindingDefaultCredentials is a bmc that accepts its vendor default credentials
+	FindingDefaultCredentials = "default_credentials"
+	// FindingAnonymousIpmi is a bmc that accepts anonymous or null username ipmi sessions
+	FindingAnonymousIpmi = "anonymous_ipmi"
+	// FindingExpiredCertificate is a bmc presenting an expired tls certificate
+	FindingExpiredCertificate = "expired_certificate"
+)
+
+// SecurityFinding is a weakness of a bmc found by the collector or the
+// scanner. It isn't stored, the findings are computed out of the auth_method
+// of the assets, the ipmi fingerprints and the certificates
+type SecurityFinding struct {
+	ID         string    `json:"-"`
+	Type       string    `json:"type"`
+	Severity   string    `json:"severity"`
+	IP         string    `json:"ip"`
+	Port       int       `json:"port"`
+	Vendor     string    `json:"vendor"`
+	Model      string    `json:"model"`
+	Detail     string    `json:"detail"`
+	DetectedAt time.Time `json:"detected_at"`
+
+	ScannedPortID  string `json:"-"`
+	ChassisSerial  string `json:"-"`
+	BladeSerial    string `json:"-"`
+	DiscreteSerial string `json:"-"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (s SecurityFinding) GetName() string {
+	return "security_findings"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (s SecurityFinding) GetID() string {
+	return s.ID
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s SecurityFinding) GetReferences() []jsonapi.Reference {
+	var references []jsonapi.Reference
+	for _, name := range []string{"scanned_ports", "chassis", "blades", "discretes"} {
+		references = append(references, jsonapi.Reference{
+			Type:         name,
+			Name:         name,
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+	return references
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface
+func (s SecurityFinding) GetReferencedIDs() []jsonapi.ReferenceID {
+	var result []jsonapi.ReferenceID
+	for i, id := range []string{s.ScannedPortID, s.ChassisSerial, s.BladeSerial, s.DiscreteSerial} {
+		if id == "" {
+			continue
+		}
+		name := []string{"scanned_ports", "chassis", "blades", "discretes"}[i]
+		result = append(result, jsonapi.ReferenceID{
+			ID:           id,
+			Type:         name,
+			Name:         name,
+			Relationship: jsonapi.ToOneRelationship,
+		})
+	}
+	return result
+}
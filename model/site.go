@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Site groups the subnets of the subnet source that belong to it, the
+// counters are the totals of its subnets
+type Site struct {
+	Name               string     `json:"name"`
+	Subnets            int        `json:"subnets"`
+	HostsWithOpenPorts int        `json:"hosts_with_open_ports"`
+	BmcsCollected      int        `json:"bmcs_collected"`
+	LastScanAt         *time.Time `json:"last_scan_at"`
+
+	CIDRs []string `json:"-"`
+}
+
+// GetName to satisfy jsonapi naming schema
+func (s Site) GetName() string {
+	return "sites"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (s Site) GetID() string {
+	return s.Name
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s Site) GetReferences() []jsonapi.Reference {
+	return append(assetReferences(), jsonapi.Reference{
+		Type:         "subnets",
+		Name:         "subnets",
+		Relationship: jsonapi.ToManyRelationship,
+	})
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface,
+// the scanned ports and the assets are too many to be listed and are only linked
+func (s Site) GetReferencedIDs() []jsonapi.ReferenceID {
+	var result []jsonapi.ReferenceID
+	for _, cidr := range s.CIDRs {
+		result = append(result, jsonapi.ReferenceID{
+			ID:           SubnetID(cidr),
+			Type:         "subnets",
+			Name:         "subnets",
+			Relationship: jsonapi.ToManyRelationship,
+		})
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+/* READ THIS BEFORE CHANGING THE SCHEMA
+
+To make the magic of dynamic filtering work, we need to define each json field matching the database column name
+
+*/
+
+// Subnet is a network of the subnet source with the coverage dora has of it.
+// It isn't stored, the counters are computed out of the scanned ports, the
+// collected assets and the scan runs of the network
+type Subnet struct {
+	CIDR               string     `json:"cidr"`
+	Site               string     `json:"site"`
+	HostsWithOpenPorts int        `json:"hosts_with_open_ports"`
+	BmcsCollected      int        `json:"bmcs_collected"`
+	LastScanAt         *time.Time `json:"last_scan_at"`
+}
+
+// SubnetID returns the id of the network within urls, 10.0.0.0/24 is 10.0.0.0-24
+func SubnetID(cidr string) string {
+	return strings.Replace(cidr, "/", "-", 1)
+}
+
+// SubnetCIDR returns the network of the subnet id
+func SubnetCIDR(id string) string {
+	if i := strings.LastIndex(id, "-"); i != -1 {
+		return id[:i] + "/" + id[i+1:]
+	}
+	return id
+}
+
+// GetName to satisfy jsonapi naming schema
+func (s Subnet) GetName() string {
+	return "subnets"
+}
+
+// GetID to satisfy jsonapi.MarshalIdentifier interface
+func (s Subnet) GetID() string {
+	return SubnetID(s.CIDR)
+}
+
+// GetReferences to satisfy the jsonapi.MarshalReferences interface
+func (s Subnet) GetReferences() []jsonapi.Reference {
+	return append(assetReferences(), jsonapi.Reference{
+		Type:         "sites",
+		Name:         "sites",
+		Relationship: jsonapi.ToOneRelationship,
+	})
+}
+
+// GetReferencedIDs to satisfy the jsonapi.MarshalLinkedRelations interface,
+// the scanned ports and the assets are too many to be listed and are only linked
+func (s Subnet) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{{
+		ID:           s.Site,
+		Type:         "sites",
+		Name:         "sites",
+		Relationship: jsonapi.ToOneRelationship,
+	}}
+}
+
+// assetReferences are the relationships of subnets and sites to the scanned
+// ports and to the assets whose bmc_address is within them
+func assetReferences() []jsonapi.Reference {
+	var references []jsonapi.Reference
+	for _, name := range []string{"scanned_ports", "chassis", "blades", "discretes"} {
+		references = append(references, jsonapi.Reference{
+			Type:         name,
+			Name:         name,
+			Relationship: jsonapi.ToManyRelationship,
+		})
+	}
+	return references
+}
@@ -12,7 +12,8 @@ import (
 
 // BladeResource for api2go routes
 type BladeResource struct {
-	BladeStorage *storage.BladeStorage
+	BladeStorage  *storage.BladeStorage
+	SubnetStorage *storage.SubnetStorage
 }
 
 // FindAll Blades
@@ -105,7 +106,18 @@ func (b BladeResource) queryAndCountAllWrapper(r api2go.Request) (count int, bla
 		}
 	}
 
-	if !hasFilters && !hasChassis && !hasInclude && !hasNIC && !hasStorageBlade && !hasDisk {
+	cidrs, hasNetwork, err := relatedNetworks(b.SubnetStorage, r)
+	if err != nil {
+		return count, blades, err
+	}
+	if hasNetwork {
+		count, blades, err = b.BladeStorage.GetAllByNetworks(offset, limit, cidrs)
+		if err != nil {
+			return count, blades, err
+		}
+	}
+
+	if !hasFilters && !hasChassis && !hasInclude && !hasNIC && !hasStorageBlade && !hasDisk && !hasNetwork {
 		count, blades, err = b.BladeStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, blades, err
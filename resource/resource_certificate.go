@@ -0,0 +1,106 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// CertificateResource for api2go routes
+type CertificateResource struct {
+	CertificateStorage *storage.CertificateStorage
+}
+
+// FindAll Certificates
+func (c CertificateResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, certificates, err := c.queryAndCountAllWrapper(r)
+	return &Response{Res: certificates}, err
+}
+
+// FindOne Certificate
+func (c CertificateResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := c.CertificateStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load Certificates in chunks
+func (c CertificateResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, certificates, err := c.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: certificates}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (c CertificateResource) queryAndCountAllWrapper(r api2go.Request) (count int, certificates []model.Certificate, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, certificates, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	// expires_within=N returns the certificates expiring within the next N days, expired ones included
+	expiresWithin, hasExpiresWithin := r.QueryParams["expires_within"]
+	if hasExpiresWithin {
+		days, err := strconv.Atoi(expiresWithin[0])
+		if err != nil || days < 0 {
+			return count, certificates, api2go.NewHTTPError(err, fmt.Sprintf("Invalid expires_within: %s", expiresWithin[0]), http.StatusBadRequest)
+		}
+		count, certificates, err = c.CertificateStorage.GetAllExpiringBefore(offset, limit, time.Now().AddDate(0, 0, days), filters)
+		filters.Clean()
+		return count, certificates, err
+	}
+
+	if hasFilters {
+		count, certificates, err = c.CertificateStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, certificates, err
+		}
+	}
+
+	scannedPortsID, hasScannedPort := r.QueryParams["scanned_portsID"]
+	if hasScannedPort {
+		count, certificates, err = c.CertificateStorage.GetAllByScannedPortsID(offset, limit, scannedPortsID)
+		return count, certificates, err
+	}
+
+	chassisID, hasChassis := r.QueryParams["chassisID"]
+	if hasChassis {
+		count, certificates, err = c.CertificateStorage.GetAllByChassisID(offset, limit, chassisID)
+		return count, certificates, err
+	}
+
+	bladesID, hasBlade := r.QueryParams["bladesID"]
+	if hasBlade {
+		count, certificates, err = c.CertificateStorage.GetAllByBladesID(offset, limit, bladesID)
+		return count, certificates, err
+	}
+
+	discretesID, hasDiscrete := r.QueryParams["discretesID"]
+	if hasDiscrete {
+		count, certificates, err = c.CertificateStorage.GetAllByDiscretesID(offset, limit, discretesID)
+		return count, certificates, err
+	}
+
+	if !hasFilters {
+		count, certificates, err = c.CertificateStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, certificates, err
+		}
+	}
+
+	return count, certificates, err
+}
@@ -13,6 +13,7 @@ import (
 // ChassisResource for api2go routes
 type ChassisResource struct {
 	ChassisStorage *storage.ChassisStorage
+	SubnetStorage  *storage.SubnetStorage
 }
 
 // FindAll Chassis
@@ -113,7 +114,18 @@ func (c ChassisResource) queryAndCountAllWrapper(r api2go.Request) (count int, c
 		}
 	}
 
-	if !hasFilters && !hasInclude && !hasBlade && !hasStorageBlade && !hasPSU && !hasNIC && !hasFAN {
+	cidrs, hasNetwork, err := relatedNetworks(c.SubnetStorage, r)
+	if err != nil {
+		return count, chassis, err
+	}
+	if hasNetwork {
+		count, chassis, err = c.ChassisStorage.GetAllByNetworks(offset, limit, cidrs)
+		if err != nil {
+			return count, chassis, err
+		}
+	}
+
+	if !hasFilters && !hasInclude && !hasBlade && !hasStorageBlade && !hasPSU && !hasNIC && !hasFAN && !hasNetwork {
 		count, chassis, err = c.ChassisStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, chassis, err
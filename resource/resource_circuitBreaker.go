@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// CircuitBreakerResource for api2go routes
+type CircuitBreakerResource struct {
+	CircuitBreakerStorage *storage.CircuitBreakerStorage
+}
+
+// FindAll CircuitBreakers
+func (c CircuitBreakerResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, breakers, err := c.queryAndCountAllWrapper(r)
+	return &Response{Res: breakers}, err
+}
+
+// FindOne CircuitBreaker
+func (c CircuitBreakerResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := c.CircuitBreakerStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load CircuitBreakers in chunks
+func (c CircuitBreakerResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, breakers, err := c.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: breakers}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (c CircuitBreakerResource) queryAndCountAllWrapper(r api2go.Request) (count int, breakers []model.CircuitBreaker, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, breakers, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, breakers, err = c.CircuitBreakerStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, breakers, err
+		}
+	}
+
+	if !hasFilters {
+		count, breakers, err = c.CircuitBreakerStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, breakers, err
+		}
+	}
+
+	return count, breakers, err
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// CollectionAttemptResource for api2go routes
+type CollectionAttemptResource struct {
+	CollectionAttemptStorage *storage.CollectionAttemptStorage
+}
+
+// FindAll CollectionAttempts
+func (c CollectionAttemptResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, attempts, err := c.queryAndCountAllWrapper(r)
+	return &Response{Res: attempts}, err
+}
+
+// FindOne CollectionAttempt
+func (c CollectionAttemptResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := c.CollectionAttemptStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load CollectionAttempts in chunks
+func (c CollectionAttemptResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, attempts, err := c.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: attempts}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (c CollectionAttemptResource) queryAndCountAllWrapper(r api2go.Request) (count int, attempts []model.CollectionAttempt, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, attempts, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, attempts, err = c.CollectionAttemptStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, attempts, err
+		}
+	}
+
+	chassisID, hasChassis := r.QueryParams["chassisID"]
+	if hasChassis {
+		count, attempts, err = c.CollectionAttemptStorage.GetAllByChassisID(offset, limit, chassisID)
+		return count, attempts, err
+	}
+
+	bladesID, hasBlade := r.QueryParams["bladesID"]
+	if hasBlade {
+		count, attempts, err = c.CollectionAttemptStorage.GetAllByBladesID(offset, limit, bladesID)
+		return count, attempts, err
+	}
+
+	discretesID, hasDiscrete := r.QueryParams["discretesID"]
+	if hasDiscrete {
+		count, attempts, err = c.CollectionAttemptStorage.GetAllByDiscretesID(offset, limit, discretesID)
+		return count, attempts, err
+	}
+
+	if !hasFilters {
+		count, attempts, err = c.CollectionAttemptStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, attempts, err
+		}
+	}
+
+	return count, attempts, err
+}
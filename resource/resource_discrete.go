@@ -13,6 +13,7 @@ import (
 // DiscreteResource for api2go routes
 type DiscreteResource struct {
 	DiscreteStorage *storage.DiscreteStorage
+	SubnetStorage   *storage.SubnetStorage
 }
 
 // FindAll Discretes
@@ -97,7 +98,18 @@ func (d DiscreteResource) queryAndCountAllWrapper(r api2go.Request) (count int,
 		}
 	}
 
-	if !hasFilters && !hasInclude && !hasNIC && !hasDisk && !hasPSU {
+	cidrs, hasNetwork, err := relatedNetworks(d.SubnetStorage, r)
+	if err != nil {
+		return count, discretes, err
+	}
+	if hasNetwork {
+		count, discretes, err = d.DiscreteStorage.GetAllByNetworks(offset, limit, cidrs)
+		if err != nil {
+			return count, discretes, err
+		}
+	}
+
+	if !hasFilters && !hasInclude && !hasNIC && !hasDisk && !hasPSU && !hasNetwork {
 		count, discretes, err = d.DiscreteStorage.GetAll(offset, limit)
 		if err != nil {
 			return count, discretes, err
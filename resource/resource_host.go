@@ -0,0 +1,64 @@
+package resource
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+	"net/http"
+)
+
+// HostResource for api2go routes
+type HostResource struct {
+	HostStorage *storage.HostStorage
+}
+
+// FindAll Hosts
+func (s HostResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, hosts, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: hosts}, err
+}
+
+// FindOne Host
+func (s HostResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	host, err := s.HostStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: host}, err
+}
+
+// PaginatedFindAll can be used to load Hosts in chunks
+func (s HostResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, hosts, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: hosts}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s HostResource) queryAndCountAllWrapper(r api2go.Request) (count int, hosts []model.Host, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, hosts, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, hosts, err = s.HostStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, hosts, err
+		}
+	} else {
+		count, hosts, err = s.HostStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, hosts, err
+		}
+	}
+
+	return count, hosts, err
+}
@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// HTTPFingerprintResource for api2go routes
+type HTTPFingerprintResource struct {
+	HTTPFingerprintStorage *storage.HTTPFingerprintStorage
+}
+
+// FindAll HTTPFingerprints
+func (h HTTPFingerprintResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, fingerprints, err := h.queryAndCountAllWrapper(r)
+	return &Response{Res: fingerprints}, err
+}
+
+// FindOne HTTPFingerprint
+func (h HTTPFingerprintResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := h.HTTPFingerprintStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load HTTPFingerprints in chunks
+func (h HTTPFingerprintResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, fingerprints, err := h.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: fingerprints}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (h HTTPFingerprintResource) queryAndCountAllWrapper(r api2go.Request) (count int, fingerprints []model.HTTPFingerprint, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, fingerprints, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, fingerprints, err = h.HTTPFingerprintStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	scannedPortsID, hasScannedPort := r.QueryParams["scanned_portsID"]
+	if hasScannedPort {
+		count, fingerprints, err = h.HTTPFingerprintStorage.GetAllByScannedPortsID(offset, limit, scannedPortsID)
+		return count, fingerprints, err
+	}
+
+	if !hasFilters {
+		count, fingerprints, err = h.HTTPFingerprintStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	return count, fingerprints, err
+}
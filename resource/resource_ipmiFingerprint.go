@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// IpmiFingerprintResource for api2go routes
+type IpmiFingerprintResource struct {
+	IpmiFingerprintStorage *storage.IpmiFingerprintStorage
+}
+
+// FindAll IpmiFingerprints
+func (i IpmiFingerprintResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, fingerprints, err := i.queryAndCountAllWrapper(r)
+	return &Response{Res: fingerprints}, err
+}
+
+// FindOne IpmiFingerprint
+func (i IpmiFingerprintResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := i.IpmiFingerprintStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load IpmiFingerprints in chunks
+func (i IpmiFingerprintResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, fingerprints, err := i.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: fingerprints}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (i IpmiFingerprintResource) queryAndCountAllWrapper(r api2go.Request) (count int, fingerprints []model.IpmiFingerprint, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, fingerprints, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, fingerprints, err = i.IpmiFingerprintStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	scannedPortsID, hasScannedPort := r.QueryParams["scanned_portsID"]
+	if hasScannedPort {
+		count, fingerprints, err = i.IpmiFingerprintStorage.GetAllByScannedPortsID(offset, limit, scannedPortsID)
+		return count, fingerprints, err
+	}
+
+	if !hasFilters {
+		count, fingerprints, err = i.IpmiFingerprintStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	return count, fingerprints, err
+}
@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// PortEventResource for api2go routes
+type PortEventResource struct {
+	PortEventStorage *storage.PortEventStorage
+}
+
+// FindAll PortEvents
+func (p PortEventResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, events, err := p.queryAndCountAllWrapper(r)
+	return &Response{Res: events}, err
+}
+
+// FindOne PortEvent
+func (p PortEventResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := p.PortEventStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load PortEvents in chunks
+func (p PortEventResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, events, err := p.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: events}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (p PortEventResource) queryAndCountAllWrapper(r api2go.Request) (count int, events []model.PortEvent, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, events, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, events, err = p.PortEventStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, events, err
+		}
+	}
+
+	scannedPortsID, hasScannedPort := r.QueryParams["scanned_portsID"]
+	if hasScannedPort {
+		count, events, err = p.PortEventStorage.GetAllByScannedPortsID(offset, limit, scannedPortsID)
+		return count, events, err
+	}
+
+	if !hasFilters {
+		count, events, err = p.PortEventStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, events, err
+		}
+	}
+
+	return count, events, err
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// ScanRunResource for api2go routes
+type ScanRunResource struct {
+	ScanRunStorage *storage.ScanRunStorage
+}
+
+// FindAll ScanRuns
+func (s ScanRunResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, runs, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: runs}, err
+}
+
+// FindOne ScanRun
+func (s ScanRunResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := s.ScanRunStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load ScanRuns in chunks
+func (s ScanRunResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, runs, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: runs}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s ScanRunResource) queryAndCountAllWrapper(r api2go.Request) (count int, runs []model.ScanRun, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, runs, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, runs, err = s.ScanRunStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, runs, err
+		}
+	}
+
+	scanRunSubnetsID, hasScanRunSubnet := r.QueryParams["scan_run_subnetsID"]
+	if hasScanRunSubnet {
+		count, runs, err = s.ScanRunStorage.GetAllByScanRunSubnetsID(offset, limit, scanRunSubnetsID)
+		return count, runs, err
+	}
+
+	cidr, hasCIDR := r.QueryParams["cidr"]
+	if hasCIDR {
+		count, runs, err = s.ScanRunStorage.GetAllByCIDR(offset, limit, cidr)
+		return count, runs, err
+	}
+
+	site, hasSite := r.QueryParams["site"]
+	if hasSite {
+		count, runs, err = s.ScanRunStorage.GetAllBySite(offset, limit, site)
+		return count, runs, err
+	}
+
+	if !hasFilters {
+		count, runs, err = s.ScanRunStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, runs, err
+		}
+	}
+
+	return count, runs, err
+}
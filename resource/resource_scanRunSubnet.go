@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// ScanRunSubnetResource for api2go routes
+type ScanRunSubnetResource struct {
+	ScanRunSubnetStorage *storage.ScanRunSubnetStorage
+}
+
+// FindAll ScanRunSubnets
+func (s ScanRunSubnetResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, subnets, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: subnets}, err
+}
+
+// FindOne ScanRunSubnet
+func (s ScanRunSubnetResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := s.ScanRunSubnetStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load ScanRunSubnets in chunks
+func (s ScanRunSubnetResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, subnets, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: subnets}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s ScanRunSubnetResource) queryAndCountAllWrapper(r api2go.Request) (count int, subnets []model.ScanRunSubnet, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, subnets, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, subnets, err = s.ScanRunSubnetStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, subnets, err
+		}
+	}
+
+	scanRunsID, hasScanRun := r.QueryParams["scan_runsID"]
+	if hasScanRun {
+		count, subnets, err = s.ScanRunSubnetStorage.GetAllByScanRunsID(offset, limit, scanRunsID)
+		return count, subnets, err
+	}
+
+	if !hasFilters {
+		count, subnets, err = s.ScanRunSubnetStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, subnets, err
+		}
+	}
+
+	return count, subnets, err
+}
@@ -48,17 +48,48 @@ func (s ScannedPortResource) queryAndCountAllWrapper(r api2go.Request) (count in
 
 	filters, hasFilters := filter.NewFilterSet(&r)
 	offset, limit := filter.OffSetAndLimitParse(&r)
+	include := r.QueryParams["include"]
 
 	if hasFilters {
-		count, scans, err = s.ScannedPortStorage.GetAllByFilters(offset, limit, filters)
+		count, scans, err = s.ScannedPortStorage.GetAllByFilters(offset, limit, filters, include)
 		filters.Clean()
 		if err != nil {
 			return count, scans, err
 		}
 	}
 
+	ipmiFingerprintsID, hasIpmiFingerprint := r.QueryParams["ipmi_fingerprintsID"]
+	if hasIpmiFingerprint {
+		count, scans, err = s.ScannedPortStorage.GetAllByIpmiFingerprintsID(offset, limit, ipmiFingerprintsID, include)
+		return count, scans, err
+	}
+
+	httpFingerprintsID, hasHTTPFingerprint := r.QueryParams["http_fingerprintsID"]
+	if hasHTTPFingerprint {
+		count, scans, err = s.ScannedPortStorage.GetAllByHTTPFingerprintsID(offset, limit, httpFingerprintsID, include)
+		return count, scans, err
+	}
+
+	certificatesID, hasCertificate := r.QueryParams["certificatesID"]
+	if hasCertificate {
+		count, scans, err = s.ScannedPortStorage.GetAllByCertificatesID(offset, limit, certificatesID, include)
+		return count, scans, err
+	}
+
+	subnetsID, hasSubnet := r.QueryParams["subnetsID"]
+	if hasSubnet {
+		count, scans, err = s.ScannedPortStorage.GetAllBySubnetsID(offset, limit, subnetsID, include)
+		return count, scans, err
+	}
+
+	sitesID, hasSite := r.QueryParams["sitesID"]
+	if hasSite {
+		count, scans, err = s.ScannedPortStorage.GetAllBySitesID(offset, limit, sitesID, include)
+		return count, scans, err
+	}
+
 	if !hasFilters {
-		count, scans, err = s.ScannedPortStorage.GetAll(offset, limit)
+		count, scans, err = s.ScannedPortStorage.GetAll(offset, limit, include)
 		if err != nil {
 			return count, scans, err
 		}
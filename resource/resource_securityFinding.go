@@ -0,0 +1,91 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// SecurityFindingResource for api2go routes
+type SecurityFindingResource struct {
+	SecurityFindingStorage *storage.SecurityFindingStorage
+}
+
+// FindAll SecurityFindings
+func (s SecurityFindingResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, findings, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: findings}, err
+}
+
+// FindOne SecurityFinding
+func (s SecurityFindingResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := s.SecurityFindingStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load SecurityFindings in chunks
+func (s SecurityFindingResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, findings, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: findings}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s SecurityFindingResource) queryAndCountAllWrapper(r api2go.Request) (count int, findings []model.SecurityFinding, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, findings, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, findings, err = s.SecurityFindingStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, findings, err
+		}
+	}
+
+	scannedPortsID, hasScannedPort := r.QueryParams["scanned_portsID"]
+	if hasScannedPort {
+		count, findings, err = s.SecurityFindingStorage.GetAllByScannedPortsID(offset, limit, scannedPortsID)
+		return count, findings, err
+	}
+
+	chassisID, hasChassis := r.QueryParams["chassisID"]
+	if hasChassis {
+		count, findings, err = s.SecurityFindingStorage.GetAllByChassisID(offset, limit, chassisID)
+		return count, findings, err
+	}
+
+	bladesID, hasBlade := r.QueryParams["bladesID"]
+	if hasBlade {
+		count, findings, err = s.SecurityFindingStorage.GetAllByBladesID(offset, limit, bladesID)
+		return count, findings, err
+	}
+
+	discretesID, hasDiscrete := r.QueryParams["discretesID"]
+	if hasDiscrete {
+		count, findings, err = s.SecurityFindingStorage.GetAllByDiscretesID(offset, limit, discretesID)
+		return count, findings, err
+	}
+
+	if !hasFilters {
+		count, findings, err = s.SecurityFindingStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, findings, err
+		}
+	}
+
+	return count, findings, err
+}
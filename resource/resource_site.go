@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// SiteResource for api2go routes
+type SiteResource struct {
+	SiteStorage *storage.SiteStorage
+}
+
+// FindAll Sites
+func (s SiteResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, sites, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: sites}, err
+}
+
+// FindOne Site
+func (s SiteResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := s.SiteStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load Sites in chunks
+func (s SiteResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, sites, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: sites}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s SiteResource) queryAndCountAllWrapper(r api2go.Request) (count int, sites []model.Site, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, sites, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, sites, err = s.SiteStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, sites, err
+		}
+	}
+
+	subnetsID, hasSubnet := r.QueryParams["subnetsID"]
+	if hasSubnet {
+		count, sites, err = s.SiteStorage.GetAllBySubnetsID(offset, limit, subnetsID)
+		return count, sites, err
+	}
+
+	if !hasFilters {
+		count, sites, err = s.SiteStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, sites, err
+		}
+	}
+
+	return count, sites, err
+}
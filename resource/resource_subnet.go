@@ -0,0 +1,91 @@
+package resource
+
+import (
+	"net/http"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/bmc-toolbox/dora/storage"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// SubnetResource for api2go routes
+type SubnetResource struct {
+	SubnetStorage *storage.SubnetStorage
+}
+
+// FindAll Subnets
+func (s SubnetResource) FindAll(r api2go.Request) (api2go.Responder, error) {
+	_, subnets, err := s.queryAndCountAllWrapper(r)
+	return &Response{Res: subnets}, err
+}
+
+// FindOne Subnet
+func (s SubnetResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
+	res, err := s.SubnetStorage.GetOne(ID)
+	if err == gorm.ErrRecordNotFound {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+	return &Response{Res: res}, err
+}
+
+// PaginatedFindAll can be used to load Subnets in chunks
+func (s SubnetResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+	count, subnets, err := s.queryAndCountAllWrapper(r)
+	return uint(count), &Response{Res: subnets}, err
+}
+
+// queryAndCountAllWrapper retrieve the data to be used for FindAll and PaginatedFindAll in a standard way
+func (s SubnetResource) queryAndCountAllWrapper(r api2go.Request) (count int, subnets []model.Subnet, err error) {
+	for _, invalidQuery := range []string{"page[number]", "page[size]"} {
+		_, invalid := r.QueryParams[invalidQuery]
+		if invalid {
+			return count, subnets, ErrPageSizeAndNumber
+		}
+	}
+
+	filters, hasFilters := filter.NewFilterSet(&r)
+	offset, limit := filter.OffSetAndLimitParse(&r)
+
+	if hasFilters {
+		count, subnets, err = s.SubnetStorage.GetAllByFilters(offset, limit, filters)
+		filters.Clean()
+		if err != nil {
+			return count, subnets, err
+		}
+	}
+
+	sitesID, hasSite := r.QueryParams["sitesID"]
+	if hasSite {
+		count, subnets, err = s.SubnetStorage.GetAllBySitesID(offset, limit, sitesID)
+		return count, subnets, err
+	}
+
+	if !hasFilters {
+		count, subnets, err = s.SubnetStorage.GetAll(offset, limit)
+		if err != nil {
+			return count, subnets, err
+		}
+	}
+
+	return count, subnets, err
+}
+
+// relatedNetworks returns the networks of the subnets or sites the request is
+// related to, used by the assets whose bmc_address is within them
+func relatedNetworks(subnetStorage *storage.SubnetStorage, r api2go.Request) (cidrs []string, related bool, err error) {
+	if subnetsID, hasSubnet := r.QueryParams["subnetsID"]; hasSubnet {
+		for _, id := range subnetsID {
+			cidrs = append(cidrs, model.SubnetCIDR(id))
+		}
+		return cidrs, true, nil
+	}
+
+	if sitesID, hasSite := r.QueryParams["sitesID"]; hasSite && subnetStorage != nil {
+		cidrs, err = subnetStorage.CIDRsOfSites(sitesID)
+		return cidrs, true, err
+	}
+
+	return cidrs, false, nil
+}
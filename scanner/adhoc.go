@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotInSubnetSource is returned for a network the subnet source doesn't
+// know when no site was given to scan it ad hoc
+var ErrNotInSubnetSource = errors.New("network isn't in the subnet source, a site is needed to scan it ad hoc")
+
+// ErrAdHocNotAllowed is returned for an ad hoc network outside of scanner.adhoc_allowed_ranges
+var ErrAdHocNotAllowed = errors.New("network isn't within the ranges allowed for ad hoc scans")
+
+// AdHocSubnet returns the network to be scanned as part of the site although
+// it isn't in the subnet source. It must be within one of the parent ranges
+// of scanner.adhoc_allowed_ranges
+func AdHocSubnet(cidr string, site string) (*ToScan, error) {
+	if site == "" || site == "all" {
+		return nil, fmt.Errorf("%s: %w", cidr, ErrNotInSubnetSource)
+	}
+
+	toScan, err := newToScan(cidr, site)
+	if err != nil {
+		return nil, err
+	}
+	_, ipNet, _ := net.ParseCIDR(toScan.CIDR)
+	ones, _ := ipNet.Mask.Size()
+
+	for _, allowed := range viper.GetStringSlice("scanner.adhoc_allowed_ranges") {
+		_, parent, err := net.ParseCIDR(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scanner.adhoc_allowed_ranges entry %s: %w", allowed, err)
+		}
+		parentOnes, _ := parent.Mask.Size()
+		if parent.Contains(ipNet.IP) && ones >= parentOnes && len(parent.IP) == len(ipNet.IP) {
+			toScan.AdHoc = true
+			return toScan, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", toScan.CIDR, ErrAdHocNotAllowed)
+}
+
+// ErrOverlappingNetworks is returned when a network to scan is within another one
+var ErrOverlappingNetworks = errors.New("networks overlap, their addresses would be scanned twice")
+
+// ResolveSubnets returns the subnets to scan for the networks, in the same
+// order and once each: the ones of the subnet source filtered by site and,
+// for the networks the source doesn't know, ad hoc subnets of adHocSite.
+// Networks within one another are refused
+func ResolveSubnets(networks []string, site []string, adHocSite string) (subnets []*ToScan, err error) {
+	known, err := LoadSubnets(viper.GetString("scanner.subnet_source"), networks, site)
+	if err != nil {
+		return subnets, err
+	}
+
+	byCIDR := make(map[string]*ToScan, len(known))
+	for _, subnet := range known {
+		if _, ok := byCIDR[subnet.CIDR]; !ok {
+			byCIDR[subnet.CIDR] = subnet
+		}
+	}
+
+	var resolved []*net.IPNet
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicate := false
+		for _, other := range resolved {
+			if other.String() == ipNet.String() {
+				duplicate = true
+				break
+			}
+			if other.Contains(ipNet.IP) || ipNet.Contains(other.IP) {
+				return nil, fmt.Errorf("%s and %s: %w", other, ipNet, ErrOverlappingNetworks)
+			}
+		}
+		if duplicate {
+			continue
+		}
+		resolved = append(resolved, ipNet)
+
+		if subnet, ok := byCIDR[ipNet.String()]; ok {
+			subnets = append(subnets, subnet)
+			continue
+		}
+
+		subnet, err := AdHocSubnet(network, adHocSite)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResolveSubnets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("subnets:\n  - cidr: 192.168.0.0/24\n    site: adc1\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "subnets.yaml"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("scanner.subnet_source", "file")
+	viper.Set("scanner.subnet_file", filepath.Join(dir, "subnets.yaml"))
+	viper.Set("scanner.adhoc_allowed_ranges", []string{"10.20.0.0/16", "2001:db8::/48"})
+	defer func() {
+		viper.Set("scanner.subnet_source", nil)
+		viper.Set("scanner.subnet_file", nil)
+		viper.Set("scanner.adhoc_allowed_ranges", nil)
+	}()
+
+	subnets, err := ResolveSubnets([]string{"10.20.1.0/24", "192.168.0.0/24", "2001:db8:0:1::/64"}, []string{"all"}, "ams4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 3 {
+		t.Fatalf("expected 3 subnets, found %+v", subnets)
+	}
+	if subnets[0].CIDR != "10.20.1.0/24" || subnets[0].Site != "ams4" || !subnets[0].AdHoc {
+		t.Errorf("expected 10.20.1.0/24 to be scanned ad hoc in ams4, found %+v", subnets[0])
+	}
+	if subnets[1].CIDR != "192.168.0.0/24" || subnets[1].Site != "adc1" || subnets[1].AdHoc {
+		t.Errorf("expected 192.168.0.0/24 to come from the subnet source, found %+v", subnets[1])
+	}
+	if subnets[2].CIDR != "2001:db8:0:1::/64" || !subnets[2].AdHoc {
+		t.Errorf("expected 2001:db8:0:1::/64 to be scanned ad hoc, found %+v", subnets[2])
+	}
+
+	subnets, err = ResolveSubnets([]string{"192.168.0.0/24", "10.20.1.0/24", "192.168.0.1/24"}, []string{"all"}, "ams4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 2 || subnets[0].CIDR != "192.168.0.0/24" || subnets[1].CIDR != "10.20.1.0/24" {
+		t.Errorf("expected the duplicate network to be scanned once, found %+v", subnets)
+	}
+
+	tt := []struct {
+		network string
+		site    string
+		err     error
+	}{
+		{"10.20.1.0/24", "", ErrNotInSubnetSource},
+		{"10.21.0.0/24", "ams4", ErrAdHocNotAllowed},
+		{"10.0.0.0/8", "ams4", ErrAdHocNotAllowed},
+		{"10.20.0.0/16,10.20.1.0/24", "ams4", ErrOverlappingNetworks},
+		{"10.20.1.128/25,10.20.1.0/24", "ams4", ErrOverlappingNetworks},
+	}
+
+	for _, tc := range tt {
+		if _, err := ResolveSubnets(strings.Split(tc.network, ","), []string{"all"}, tc.site); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, found %v", tc.network, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// exclusion is an entry of an exclusion list with the addresses it covers
+type exclusion struct {
+	entry       string
+	first, last net.IP
+}
+
+// Exclusions are the addresses of a subnet that must never be probed nor
+// collected, e.g. gateways, HSRP/VRRP ranges or fragile devices. Entries are
+// addresses, networks, first-last ranges or offsets within the subnet: +N from
+// the network address and -N from the broadcast address
+type Exclusions struct {
+	ipNet      *net.IPNet
+	exclusions []exclusion
+}
+
+// ExclusionsFor returns the exclusions of the subnet: scanner.exclude,
+// scanner.sites.<site>.exclude and the ones set by the subnet source
+func ExclusionsFor(subnet *ToScan) (*Exclusions, error) {
+	_, ipNet, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exclusions{ipNet: ipNet}
+	entries := append(viper.GetStringSlice("scanner.exclude"), viper.GetStringSlice(fmt.Sprintf("scanner.sites.%s.exclude", subnet.Site))...)
+	for _, entry := range append(entries, subnet.Exclude...) {
+		x, err := parseExclusion(entry, ipNet)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", subnet.CIDR, err)
+		}
+		// global entries elsewhere don't concern this subnet
+		if x.first == nil || bytes.Compare(x.last, ipNet.IP.To16()) < 0 || bytes.Compare(x.first, lastAddress(ipNet)) > 0 {
+			continue
+		}
+		e.exclusions = append(e.exclusions, x)
+	}
+
+	return e, nil
+}
+
+// Contains tells whether the address is excluded
+func (e *Exclusions) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if e == nil || parsed == nil {
+		return false
+	}
+
+	addr := parsed.To16()
+	for _, x := range e.exclusions {
+		if bytes.Compare(addr, x.first) >= 0 && bytes.Compare(addr, x.last) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns the exclusion entries that concern the subnet
+func (e *Exclusions) Entries() (entries []string) {
+	for _, x := range e.exclusions {
+		entries = append(entries, x.entry)
+	}
+	return entries
+}
+
+func parseExclusion(entry string, ipNet *net.IPNet) (x exclusion, err error) {
+	entry = strings.TrimSpace(entry)
+	x.entry = entry
+
+	switch {
+	case strings.HasPrefix(entry, "+") || strings.HasPrefix(entry, "-"):
+		offset, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil {
+			return x, fmt.Errorf("invalid exclusion offset %s", entry)
+		}
+		base := ipNet.IP.To16()
+		if offset < 0 {
+			base = lastAddress(ipNet)
+		}
+		// offsets beyond small subnets, e.g. -3 of a /30, exclude nothing
+		first := addToIP(base, offset)
+		if first == nil || !ipNet.Contains(first) {
+			return x, nil
+		}
+		x.first, x.last = first, first
+	case strings.Contains(entry, "/"):
+		_, excluded, err := net.ParseCIDR(entry)
+		if err != nil {
+			return x, err
+		}
+		x.first, x.last = excluded.IP.To16(), lastAddress(excluded)
+	case strings.Contains(entry, "-"):
+		bounds := strings.SplitN(entry, "-", 2)
+		first, last := net.ParseIP(strings.TrimSpace(bounds[0])), net.ParseIP(strings.TrimSpace(bounds[1]))
+		if first == nil || last == nil || bytes.Compare(first.To16(), last.To16()) > 0 {
+			return x, fmt.Errorf("invalid exclusion range %s", entry)
+		}
+		x.first, x.last = first.To16(), last.To16()
+	default:
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return x, fmt.Errorf("invalid exclusion %s", entry)
+		}
+		x.first, x.last = ip.To16(), ip.To16()
+	}
+
+	return x, nil
+}
+
+// lastAddress returns the broadcast address of the network
+func lastAddress(ipNet *net.IPNet) net.IP {
+	ip := ipNet.IP.To16()
+	last := make(net.IP, len(ip))
+	mask := ipNet.Mask
+	if len(mask) == net.IPv4len {
+		mask = append(net.CIDRMask(96, 128)[:12], mask...)
+	}
+	for i := range ip {
+		last[i] = ip[i] | ^mask[i]
+	}
+	return last
+}
+
+func addToIP(ip net.IP, offset int64) net.IP {
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip), big.NewInt(offset))
+	if sum.Sign() < 0 || sum.BitLen() > 128 {
+		return nil
+	}
+	out := make(net.IP, net.IPv6len)
+	b := sum.Bytes()
+	copy(out[net.IPv6len-len(b):], b)
+	return out
+}
+
+// ExclusionChecker tells whether an address is excluded, whichever subnet of
+// the subnet source it belongs to. Ad hoc subnets aren't in the source, only
+// the global entries of scanner.exclude that aren't offsets apply to them
+type ExclusionChecker struct {
+	subnets []*Exclusions
+	global  []exclusion
+}
+
+// NewExclusionChecker loads the subnets of the subnet source and their exclusions
+func NewExclusionChecker() (*ExclusionChecker, error) {
+	subnets, err := ListSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		return nil, err
+	}
+	return newExclusionChecker(subnets)
+}
+
+// newExclusionChecker builds the checker of the already loaded subnets
+func newExclusionChecker(subnets []*ToScan) (*ExclusionChecker, error) {
+	c := &ExclusionChecker{}
+	for _, entry := range viper.GetStringSlice("scanner.exclude") {
+		// offsets only make sense within a subnet
+		if strings.HasPrefix(entry, "+") || strings.HasPrefix(entry, "-") {
+			continue
+		}
+		x, err := parseExclusion(entry, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.global = append(c.global, x)
+	}
+
+	for _, subnet := range subnets {
+		exclusions, err := ExclusionsFor(subnet)
+		if err != nil {
+			return nil, err
+		}
+		c.subnets = append(c.subnets, exclusions)
+	}
+
+	return c, nil
+}
+
+// Excluded tells whether the address is excluded
+func (c *ExclusionChecker) Excluded(ip string) bool {
+	if (&Exclusions{exclusions: c.global}).Contains(ip) {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, exclusions := range c.subnets {
+		if exclusions.ipNet.Contains(parsed) && exclusions.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestExclusionsFor(t *testing.T) {
+	viper.Set("scanner.exclude", []string{"+1", "10.9.0.0/16"})
+	viper.Set("scanner.sites", map[string]interface{}{
+		"adc1": map[string]interface{}{"exclude": []string{"192.168.0.252-192.168.0.254"}},
+	})
+	defer func() {
+		viper.Set("scanner.exclude", nil)
+		viper.Set("scanner.sites", nil)
+	}()
+
+	subnet := &ToScan{CIDR: "192.168.0.0/24", Site: "adc1", Exclude: []string{"192.168.0.17", "-2"}}
+	exclusions, err := ExclusionsFor(subnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"+1", "192.168.0.252-192.168.0.254", "192.168.0.17", "-2"}
+	if !reflect.DeepEqual(exclusions.Entries(), expected) {
+		t.Errorf("expected the entries %v, found %v", expected, exclusions.Entries())
+	}
+
+	for ip, excluded := range map[string]bool{
+		"192.168.0.1":   true,
+		"192.168.0.2":   false,
+		"192.168.0.17":  true,
+		"192.168.0.251": false,
+		"192.168.0.252": true,
+		"192.168.0.253": true,
+		"192.168.0.254": true,
+		"10.9.1.1":      false,
+	} {
+		if exclusions.Contains(ip) != excluded {
+			t.Errorf("%s: expected excluded to be %v", ip, excluded)
+		}
+	}
+
+	var ips []string
+	if err := eachTarget(&ToScan{CIDR: "192.168.0.0/29", Site: "adc1", Exclude: []string{"192.168.0.4"}}, func(ip string) { ips = append(ips, ip) }); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ips, []string{"192.168.0.2", "192.168.0.3", "192.168.0.5", "192.168.0.6"}) {
+		t.Errorf("unexpected targets %v", ips)
+	}
+
+	exclusions, err = ExclusionsFor(&ToScan{CIDR: "192.168.0.1/32", Site: "adc1"})
+	if err != nil || len(exclusions.Entries()) != 0 {
+		t.Errorf("expected offsets beyond a /32 to be ignored, found %v %v", exclusions.Entries(), err)
+	}
+
+	for _, entry := range []string{"+x", "192.168.0.10-192.168.0.1", "bmc"} {
+		if _, err := ExclusionsFor(&ToScan{CIDR: "192.168.0.0/24", Exclude: []string{entry}}); err == nil {
+			t.Errorf("expected %s to be refused", entry)
+		}
+	}
+}
+
+func TestKeaExclusions(t *testing.T) {
+	viper.SetDefault("scanner.kea_site_key", "site")
+	viper.SetDefault("scanner.kea_exclude_key", "dora-exclude")
+	viper.SetDefault("scanner.kea_exclude_hosts_key", "dora-exclude-hosts")
+
+	config := []byte(`{"Dhcp4": {"shared-networks": [{
+		"name": "adc1",
+		"user-context": {"site": "adc1", "dora-exclude-hosts": ["+1", "+2"]},
+		"subnet4": [{
+			"subnet": "192.168.64.0/24",
+			"pools": [{"pool": "192.168.64.200 - 192.168.64.210", "user-context": {"dora-exclude": true}}, {"pool": "192.168.64.100 - 192.168.64.150"}],
+			"reservations": [
+				{"hw-address": "aa:bb:cc:dd:ee:ff", "ip-address": "192.168.64.20", "user-context": {"dora-exclude": true}},
+				{"hw-address": "aa:bb:cc:dd:ee:00", "ip-address": "192.168.64.21"}
+			]
+		}]
+	}]}}`)
+
+	subnets, err := LoadSubnetsFromKea(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"+1", "+2", "192.168.64.20", "192.168.64.200-192.168.64.210"}
+	if len(subnets) != 1 || !reflect.DeepEqual(subnets[0].Exclude, expected) {
+		t.Fatalf("expected the exclusions %v, found %+v", expected, subnets)
+	}
+}
+
+func TestExclusionChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("subnets:\n  - cidr: 192.168.0.0/24\n    site: adc1\n    exclude: [+1]\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "subnets.yaml"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("scanner.subnet_source", "file")
+	viper.Set("scanner.subnet_file", filepath.Join(dir, "subnets.yaml"))
+	viper.Set("scanner.exclude", []string{"+2", "10.0.0.5"})
+	defer func() {
+		viper.Set("scanner.subnet_source", nil)
+		viper.Set("scanner.subnet_file", nil)
+		viper.Set("scanner.exclude", nil)
+	}()
+
+	c, err := NewExclusionChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ip, excluded := range map[string]bool{
+		"192.168.0.1": true,
+		"192.168.0.2": true,
+		"192.168.0.3": false,
+		"10.0.0.5":    true,
+		"10.0.0.1":    false,
+		// ad hoc networks aren't in the source, offsets don't apply to them
+		"10.0.0.2": false,
+	} {
+		if c.Excluded(ip) != excluded {
+			t.Errorf("%s: expected excluded to be %v", ip, excluded)
+		}
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "subnets.yaml")
+	if err := ioutil.WriteFile(file, []byte("subnets:\n  - cidr: 192.168.0.0/24\n    site: adc1\n    exclude: [+1]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("scanner.subnet_source", "file")
+	viper.Set("scanner.subnet_file", file)
+	defer func() {
+		viper.Set("scanner.subnet_source", nil)
+		viper.Set("scanner.subnet_file", nil)
+	}()
+
+	c := NewSourceCache(time.Hour)
+	subnets, err := c.Subnets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 1 || subnets[0].CIDR != "192.168.0.0/24" || subnets[0].Site != "adc1" {
+		t.Fatalf("expected the subnet of the source, found %+v", subnets)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("subnets:\n  - cidr: 192.168.1.0/24\n    site: adc1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the source isn't read again before the ttl
+	exclusions, err := c.ExclusionChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exclusions.Excluded("192.168.0.1") {
+		t.Errorf("expected the cached exclusions of the source")
+	}
+
+	c.Reset()
+	if subnets, err = c.Subnets(); err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 1 || subnets[0].CIDR != "192.168.1.0/24" {
+		t.Errorf("expected the source to be read again once reset, found %+v", subnets)
+	}
+}
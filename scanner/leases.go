@@ -0,0 +1,190 @@
+package scanner
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Lease is an active lease of the kea memfile or lease database
+type Lease struct {
+	Address   string    `json:"address"`
+	HwAddress string    `json:"hw_address,omitempty"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Expire    time.Time `json:"expire"`
+}
+
+// LoadActiveLeases reads a kea memfile lease file (kea-leases4.csv or
+// kea-leases6.csv) and returns the leases that are still active. The memfile is
+// an append only log, so the last entry of each address wins
+func LoadActiveLeases(path string) (leases []*Lease, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return leases, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return leases, fmt.Errorf("unable to read the lease file header %s: %w", path, err)
+	}
+
+	columns := make(map[string]int)
+	for idx, name := range header {
+		columns[name] = idx
+	}
+	for _, required := range []string{"address", "expire"} {
+		if _, ok := columns[required]; !ok {
+			return leases, fmt.Errorf("lease file %s has no %s column", path, required)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		idx, ok := columns[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return record[idx]
+	}
+
+	now := time.Now()
+	byAddress := make(map[string]*Lease)
+	var order []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return leases, err
+		}
+
+		ip := net.ParseIP(column(record, "address"))
+		if ip == nil {
+			continue
+		}
+		address := ip.String()
+
+		if _, seen := byAddress[address]; !seen {
+			order = append(order, address)
+		}
+
+		// state 0 is the default state, 1 declined and 2 expired-reclaimed.
+		// lease_type only exists in the lease6 file, 2 is a delegated prefix
+		state := column(record, "state")
+		if state == "" {
+			state = "0"
+		}
+
+		expire, err := strconv.ParseInt(column(record, "expire"), 10, 64)
+		if err != nil || time.Unix(expire, 0).Before(now) || state != "0" || column(record, "lease_type") == "2" {
+			byAddress[address] = nil
+			continue
+		}
+
+		byAddress[address] = &Lease{
+			Address:   address,
+			HwAddress: column(record, "hwaddr"),
+			Hostname:  column(record, "hostname"),
+			Expire:    time.Unix(expire, 0),
+		}
+	}
+
+	for _, address := range order {
+		if lease := byAddress[address]; lease != nil {
+			leases = append(leases, lease)
+		}
+	}
+
+	return leases, nil
+}
+
+// LoadActiveLeasesFromDB reads the active leases from the lease4 table of a
+// kea postgres or mysql lease backend. The addresses are stored as integers
+// and the hardware addresses as raw bytes, mysql needs parseTime=true in its
+// options for the expire column
+func LoadActiveLeasesFromDB(dbType string, options string) (leases []*Lease, err error) {
+	if dbType != "postgres" && dbType != "mysql" {
+		return leases, fmt.Errorf("unsupported kea lease database: %s", dbType)
+	}
+
+	db, err := gorm.Open(dbType, options)
+	if err != nil {
+		return leases, err
+	}
+	defer db.Close()
+
+	// state 0 is the default state, declined and expired-reclaimed leases are skipped
+	rows, err := db.Raw("SELECT address, hwaddr, hostname, expire FROM lease4 WHERE state = 0 AND expire > ? ORDER BY address", time.Now()).Rows()
+	if err != nil {
+		return leases, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address int64
+		var hwaddr []byte
+		var hostname sql.NullString
+		var expire time.Time
+		if err = rows.Scan(&address, &hwaddr, &hostname, &expire); err != nil {
+			return leases, err
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(address))
+
+		lease := &Lease{Address: ip.String(), Hostname: hostname.String, Expire: expire}
+		if len(hwaddr) != 0 {
+			lease.HwAddress = net.HardwareAddr(hwaddr).String()
+		}
+		leases = append(leases, lease)
+	}
+
+	return leases, rows.Err()
+}
+
+// restrictToLeases limits the ipv4 subnets to their leased addresses, a
+// subnet without any lease has nothing to scan. Each lease is parsed once and
+// looked up by the networks of each prefix length the subnets have
+func restrictToLeases(subnets []*ToScan, leases []*Lease) {
+	byNetwork := make(map[string][]*ToScan)
+	prefixes := make(map[int]bool)
+	for _, subnet := range subnets {
+		_, ipNet, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil || isIPv6(ipNet.IP) {
+			continue
+		}
+
+		subnet.HostsOnly = true
+		subnet.Hosts = nil
+		subnet.Leases = nil
+		ones, _ := ipNet.Mask.Size()
+		prefixes[ones] = true
+		byNetwork[ipNet.String()] = append(byNetwork[ipNet.String()], subnet)
+	}
+
+	for _, lease := range leases {
+		ip := net.ParseIP(lease.Address).To4()
+		if ip == nil {
+			continue
+		}
+		for ones := range prefixes {
+			network := &net.IPNet{IP: ip.Mask(net.CIDRMask(ones, 32)), Mask: net.CIDRMask(ones, 32)}
+			for _, subnet := range byNetwork[network.String()] {
+				subnet.Hosts = append(subnet.Hosts, lease.Address)
+				subnet.Leases = append(subnet.Leases, lease)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errICMPUnavailable is returned when unprivileged icmp sockets can't be
+// used, e.g. outside of linux or with net.ipv4.ping_group_range excluding us
+var errICMPUnavailable = errors.New("unprivileged icmp sockets are unavailable")
+
+// livenessPort is connected to when icmp can't tell whether a host is alive
+var livenessPort = 443
+
+var icmpUnavailableOnce sync.Once
+
+// IsAlive tells whether something answers at the address, with an icmp echo
+// over an unprivileged datagram socket or, when icmp can't be used, a single
+// tcp connect to 443, a refused connection being an answer too. With
+// tcpFallback the tcp connect also runs for hosts that didn't reply to the
+// echo, which costs them a second timeout. When neither can tell, the host is
+// considered alive so it gets its regular probes
+func IsAlive(ip string, timeout time.Duration, tcpFallback bool) (alive bool, method string) {
+	alive, err := pingICMP(ip, timeout)
+	if err == nil && (alive || !tcpFallback) {
+		return alive, "icmp"
+	}
+	if err != nil {
+		icmpUnavailableOnce.Do(func() {
+			log.WithFields(log.Fields{"operation": "liveness check", "fallback": fmt.Sprintf("tcp/%d", livenessPort)}).Warn(err)
+		})
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(livenessPort)), timeout)
+	if err == nil {
+		conn.Close()
+		return true, "tcp"
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return true, "tcp"
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return false, "tcp"
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return false, "tcp"
+	}
+
+	log.WithFields(log.Fields{"operation": "liveness check", "host": ip}).Debug(err)
+	return true, "none"
+}
+
+// icmpEcho builds an echo request, the kernel rewrites the identifier of
+// datagram icmp sockets so only the sequence has to match
+func icmpEcho(v6 bool, seq uint16) []byte {
+	msg := []byte{8, 0, 0, 0, 0, 0, byte(seq >> 8), byte(seq), 'd', 'o', 'r', 'a'}
+	if v6 {
+		// the icmpv6 checksum covers a pseudo header, the kernel fills it in
+		msg[0] = 128
+		return msg
+	}
+
+	var sum uint32
+	for i := 0; i < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	sum = (sum >> 16) + (sum & 0xffff)
+	sum += sum >> 16
+	checksum := ^uint16(sum)
+	msg[2], msg[3] = byte(checksum>>8), byte(checksum)
+	return msg
+}
+
+// isEchoReply tells whether the message is the reply to our echo request
+func isEchoReply(v6 bool, msg []byte, seq uint16) bool {
+	if len(msg) < 8 {
+		return false
+	}
+	reply := byte(0)
+	if v6 {
+		reply = 129
+	}
+	return msg[0] == reply && msg[1] == 0 && uint16(msg[6])<<8|uint16(msg[7]) == seq
+}
@@ -0,0 +1,60 @@
+//go:build linux
+// +build linux
+
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// pingICMP sends an icmp echo through an unprivileged datagram socket and
+// waits for the reply
+func pingICMP(ip string, timeout time.Duration) (alive bool, err error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid address %s", ip)
+	}
+
+	v6 := addr.To4() == nil
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	if v6 {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", errICMPUnavailable, err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", errICMPUnavailable, err)
+	}
+	defer conn.Close()
+
+	seq := uint16(time.Now().UnixNano())
+	if _, err = conn.WriteTo(icmpEcho(v6, seq), &net.UDPAddr{IP: addr}); err != nil {
+		// unreachable networks and the like, the host is as good as dead
+		return false, nil
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false, nil
+		}
+		if udp, ok := from.(*net.UDPAddr); ok && udp.IP.Equal(addr) && isEchoReply(v6, buf[:n], seq) {
+			return true, nil
+		}
+	}
+}
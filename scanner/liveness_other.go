@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package scanner
+
+import "time"
+
+// pingICMP needs the unprivileged icmp sockets of linux
+func pingICMP(ip string, timeout time.Duration) (alive bool, err error) {
+	return false, errICMPUnavailable
+}
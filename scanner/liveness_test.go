@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestICMPEcho(t *testing.T) {
+	msg := icmpEcho(false, 0x1234)
+
+	var sum uint32
+	for i := 0; i < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	if sum != 0xffff {
+		t.Errorf("invalid checksum in %x", msg)
+	}
+
+	reply := append([]byte{}, msg...)
+	reply[0] = 0
+	if !isEchoReply(false, reply, 0x1234) {
+		t.Error("expected the reply to match the request")
+	}
+	if isEchoReply(false, reply, 0x1235) || isEchoReply(false, msg, 0x1234) {
+		t.Error("expected another sequence or the request itself not to match")
+	}
+
+	reply6 := icmpEcho(true, 7)
+	reply6[0] = 129
+	if !isEchoReply(true, reply6, 7) {
+		t.Error("expected the icmpv6 reply to match the request")
+	}
+}
+
+func TestIsAlive(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+
+	defer func(port int) { livenessPort = port }(livenessPort)
+	livenessPort = port
+
+	if alive, method := IsAlive("127.0.0.1", time.Second, true); !alive {
+		t.Errorf("expected 127.0.0.1 to be alive through %s", method)
+	}
+
+	// a refused connection is an answer as well
+	l.Close()
+	if alive, method := IsAlive("127.0.0.1", time.Second, true); !alive {
+		t.Errorf("expected 127.0.0.1 to be alive through %s once the port is closed", method)
+	}
+}
+
+func TestPipelineLiveness(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("scanner.liveness_check", true)
+	viper.Set("scanner.liveness_timeout", time.Second)
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"test": []map[string]interface{}{
+			{"protocol": "tcp", "port": 1, "timeout": "1s"},
+		},
+	})
+	defer func() {
+		viper.Set("scanner.liveness_check", false)
+		viper.Set("scanner.profiles", nil)
+	}()
+
+	var result *model.ScanRunSubnet
+	p := newPipeline(db, 2)
+	p.finished = func(r *model.ScanRunSubnet) { result = r }
+	p.Submit(&ToScan{CIDR: "127.0.0.1/32", Site: "adc1", Profile: "test"})
+	p.Wait()
+
+	if result == nil || result.HostsProbed != 1 || result.HostsAlive != 1 {
+		t.Errorf("expected 127.0.0.1 to be probed and alive, found %+v", result)
+	}
+}
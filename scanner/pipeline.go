@@ -0,0 +1,277 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// errScanInterrupted fails the subnets whose expansion was stopped
+var errScanInterrupted = errors.New("scan interrupted by a shutdown")
+
+// pipeline expands the subnets lazily into (ip, profile) probe tasks and
+// feeds them to a pool of workers shared by all the subnets, so a subnet full
+// of dead addresses doesn't hold a worker on its own for hours
+type pipeline struct {
+	db       *gorm.DB
+	subnets  chan *ToScan
+	tasks    chan *probeTask
+	limiter  *limiter
+	writer   *scanWriter
+	expander sync.WaitGroup
+	workers  sync.WaitGroup
+	// stopped is closed by Stop, mu keeps Submit from sending on closed subnets
+	stopped  chan struct{}
+	stopOnce sync.Once
+	mu       sync.RWMutex
+	// finished is called once every task of a subnet is done
+	finished func(*model.ScanRunSubnet)
+}
+
+// subnetScan tracks the probe tasks of a subnet still in flight and
+// aggregates their outcome
+type subnetScan struct {
+	subnet     *ToScan
+	profile    []*ScanOption
+	politeness *politeness
+	liveness   bool
+	pending    int64
+
+	mu     sync.Mutex
+	result *model.ScanRunSubnet
+}
+
+// probeTask is a single address to probe with every option of the profile
+type probeTask struct {
+	scan *subnetScan
+	ip   string
+	// release frees the connection slot of the /24 taken for the address
+	release func()
+}
+
+// newPipeline starts the expander and concurrency workers
+func newPipeline(db *gorm.DB, concurrency int) *pipeline {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	p := &pipeline{
+		db:      db,
+		subnets: make(chan *ToScan, concurrency),
+		tasks:   make(chan *probeTask, concurrency),
+		limiter: newLimiter(),
+		writer:  newScanWriter(db),
+		stopped: make(chan struct{}),
+		finished: func(result *model.ScanRunSubnet) {
+			finishScanRunSubnet(db, result)
+			pruneAfterScan(db, result)
+		},
+	}
+
+	p.expander.Add(1)
+	go func() {
+		defer p.expander.Done()
+		for subnet := range p.subnets {
+			p.expand(subnet)
+		}
+	}()
+	go func() {
+		// the subnets waiting on their own limits are still expanding
+		p.expander.Wait()
+		close(p.tasks)
+	}()
+
+	p.workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer p.workers.Done()
+			for task := range p.tasks {
+				probeHost(p.db, p.writer, task)
+				task.release()
+				p.done(task.scan)
+			}
+		}()
+	}
+
+	return p
+}
+
+// Submit queues a subnet to be scanned, it blocks while the pipeline is busy
+// and returns false once the pipeline is stopped
+func (p *pipeline) Submit(subnet *ToScan) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	select {
+	case <-p.stopped:
+		return false
+	default:
+	}
+
+	select {
+	case p.subnets <- subnet:
+		return true
+	case <-p.stopped:
+		return false
+	}
+}
+
+// Stop refuses the subnets submitted from now on and stops expanding the
+// ones being scanned: their addresses not queued yet aren't probed and they
+// are recorded as failed, the tasks already queued still run
+func (p *pipeline) Stop() {
+	p.stopOnce.Do(func() { close(p.stopped) })
+}
+
+// Wait closes the pipeline and waits for every submitted subnet to be
+// scanned, their results are stored by then
+func (p *pipeline) Wait() {
+	p.mu.Lock()
+	close(p.subnets)
+	p.mu.Unlock()
+	p.workers.Wait()
+}
+
+// expand turns the subnet into probe tasks, the addresses are walked as the
+// workers consume them instead of being listed upfront. A subnet with quiet
+// hours or connections_per_24 is expanded on its own so waiting on them holds
+// neither the other subnets nor the workers
+func (p *pipeline) expand(subnet *ToScan) {
+	s, err := p.prepare(subnet)
+	if err != nil {
+		p.done(s)
+		return
+	}
+
+	if !s.politeness.exclusive() {
+		p.queue(s)
+		return
+	}
+
+	p.expander.Add(1)
+	go func() {
+		defer p.expander.Done()
+		p.queue(s)
+	}()
+}
+
+// prepare starts the scan of the subnet and loads its profile and its rate limit
+func (p *pipeline) prepare(subnet *ToScan) (s *subnetScan, err error) {
+	startScanRunSubnet(p.db, subnet)
+	recordHosts(p.db, subnet)
+
+	now := time.Now()
+	s = &subnetScan{
+		subnet: subnet,
+		// the expansion itself counts as pending until every task is queued
+		pending: 1,
+		result: &model.ScanRunSubnet{
+			ScanRunID: subnet.RunID,
+			CIDR:      subnet.CIDR,
+			Site:      subnet.Site,
+			Profile:   profileName(subnet),
+			ScannedBy: viper.GetString("scanner.scanned_by"),
+			StartedAt: &now,
+		},
+	}
+
+	log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR, "ad_hoc": subnet.AdHoc}).Info("network scan started")
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "loading scan profiles", "subnet": subnet.CIDR}).Error(err)
+		failedSubnet(s.result, err)
+		return s, err
+	}
+
+	profile, ok := profiles[s.result.Profile]
+	if !ok {
+		log.WithFields(log.Fields{"operation": "loading scan profiles", "subnet": subnet.CIDR, "profile": s.result.Profile}).Error("unknown scan profile")
+		err = fmt.Errorf("unknown scan profile: %s", s.result.Profile)
+		failedSubnet(s.result, err)
+		return s, err
+	}
+	s.profile = profile
+	s.liveness = viper.GetBool("scanner.liveness_check")
+
+	if s.politeness, err = p.limiter.politeness(subnet); err != nil {
+		log.WithFields(log.Fields{"operation": "loading rate limits", "subnet": subnet.CIDR}).Error(err)
+		failedSubnet(s.result, err)
+		return s, err
+	}
+
+	return s, nil
+}
+
+// queue hands the probe tasks of the subnet to the workers, pausing during
+// its quiet hours and while the /24 of the next address has no free slot
+func (p *pipeline) queue(s *subnetScan) {
+	defer p.done(s)
+
+	subnet := s.subnet
+	interrupted := false
+	err := eachTarget(subnet, func(ip string) {
+		if interrupted || !s.politeness.waitQuietHours(p.stopped) || p.isStopped() {
+			interrupted = true
+			return
+		}
+		release := s.politeness.connect(ip)
+
+		atomic.AddInt64(&s.pending, 1)
+		s.mu.Lock()
+		s.result.HostsProbed++
+		s.mu.Unlock()
+		p.tasks <- &probeTask{scan: s, ip: ip, release: release}
+	})
+	if err == nil && interrupted {
+		err = errScanInterrupted
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Error(err)
+		s.mu.Lock()
+		failedSubnet(s.result, err)
+		s.mu.Unlock()
+	}
+}
+
+// isStopped tells whether Stop was called
+func (p *pipeline) isStopped() bool {
+	select {
+	case <-p.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// done marks a task, or the expansion, of the subnet as done and records the
+// subnet scan once nothing is pending anymore
+func (p *pipeline) done(s *subnetScan) {
+	if atomic.AddInt64(&s.pending, -1) != 0 {
+		return
+	}
+
+	// the results have to be stored before the scan is recorded, pruning relies on them
+	p.writer.flush()
+
+	log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": s.subnet.CIDR}).Info("network scan finished")
+	if s.result.State == "" {
+		s.result.State = model.ScanRunFinished
+	}
+	p.finished(s.result)
+}
+
+func failedSubnet(result *model.ScanRunSubnet, err error) *model.ScanRunSubnet {
+	result.State = model.ScanRunFailed
+	result.Errors++
+	result.Error = err.Error()
+	return result
+}
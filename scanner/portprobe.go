@@ -2,8 +2,8 @@ package scanner
 
 import (
 	"errors"
-	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -11,7 +11,8 @@ import (
 
 // Port statuses
 const (
-	closed = iota - 1
+	notProbed = iota - 2
+	closed
 	open
 )
 
@@ -29,75 +30,58 @@ func (r Result) String() string {
 		return "closed"
 	case open:
 		return "open"
+	case notProbed:
+		return "not_probed"
 	default:
 		return "unsupported"
 	}
 }
 
-// probeTCP determines whether the indicated TCP port on the target host is
-// open.
-func probeTCP(node string, port int) Result {
-	address := fmt.Sprintf("%s:%d", node, port)
-	conn, err := net.DialTimeout("tcp4", address, 1*time.Second)
-	if err != nil {
-		log.WithFields(log.Fields{"dial": "tcp", "address": address}).Debug(err)
-		return closed
-	}
-	defer conn.Close()
-	return open
-}
+// Prober determines whether a port of a host is open, returning any details
+// the protocol was able to gather on the way (banners, redirects, ...)
+type Prober func(node string, port int, timeout time.Duration) (Result, string)
 
-// probeTCP determines whether the indicated IPMI port on the target host is
-// open.
-func probeIPMI(node string, port int) Result {
-	address := fmt.Sprintf("%s:%d", node, port)
-	conn, err := net.DialTimeout("udp4", address, 1*time.Second)
-	if err != nil {
-		log.WithFields(log.Fields{"dial": "udp", "address": address}).Debug(err)
-		return closed
-	}
-	defer conn.Close()
+var probes = map[string]Prober{
+	"tcp":  probeTCP,
+	"ipmi": probeIPMI,
+	"kvm":  probeKVM,
+	"http": probeHTTP,
+	"snmp": probeSNMP,
+}
 
-	// This payload is the rmcp ping from rmcp rfc
-	payload := []byte("\x06\x00\xff\x06\x00\x00\x11\xbe\x80\x18\x00\x00")
-	_, err = conn.Write(payload)
-	if err != nil {
-		log.WithFields(log.Fields{"write": "udp", "address": address}).Debug(err)
-		return closed
-	}
+// RegisterProbe makes a Prober available to the scan profiles under the given
+// protocol name. It's not safe to call it while scanning
+func RegisterProbe(protocol string, p Prober) {
+	probes[protocol] = p
+}
 
-	err = conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-	if err != nil {
-		log.WithFields(log.Fields{"set read timeout": "udp", "address": address}).Debug(err)
-		return closed
-	}
+// IsSupportedProtocol tells whether we have a Prober for the protocol
+func IsSupportedProtocol(protocol string) bool {
+	_, ok := probes[protocol]
+	return ok
+}
 
-	buf := make([]byte, 512)
-	n, err := conn.Read(buf)
+// probeTCP determines whether the indicated TCP port on the target host is
+// open.
+func probeTCP(node string, port int, timeout time.Duration) (Result, string) {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
-		log.WithFields(log.Fields{"read": "udp", "address": address}).Debug(err)
-		if e, ok := err.(net.Error); ok && e.Timeout() {
-			return closed
-		}
-		return open
-	}
-
-	if n > 0 {
-		return open
+		log.WithFields(log.Fields{"dial": "tcp", "address": address}).Debug(err)
+		return closed, ""
 	}
-
-	return closed
+	defer conn.Close()
+	return open, ""
 }
 
 // Probe determines whether the specified port on the on the specified host is
 // potentially accepting input via the specified network protocol.
-func Probe(protocol, host string, port int) (r Result, err error) {
-	switch protocol {
-	case "tcp":
-		return probeTCP(host, port), err
-	case "ipmi":
-		return probeIPMI(host, port), err
-	default:
-		return r, ErrUnsupportedProtocol
+func Probe(protocol, host string, port int, timeout time.Duration) (r Result, details string, err error) {
+	p, ok := probes[protocol]
+	if !ok {
+		return r, details, ErrUnsupportedProtocol
 	}
+
+	r, details = p(host, port, timeout)
+	return r, details, err
 }
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// probeHTTP determines whether the indicated plain http port on the target host
+// is open. BMCs usually redirect to their https interface, when that happens
+// the redirect target is returned as details
+func probeHTTP(node string, port int, timeout time.Duration) (Result, string) {
+	url := fmt.Sprintf("http://%s/", net.JoinHostPort(node, strconv.Itoa(port)))
+
+	// the transport dials from its own goroutine
+	var connected int32
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, network, address)
+				if err == nil {
+					atomic.StoreInt32(&connected, 1)
+				}
+				return conn, err
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		log.WithFields(log.Fields{"get": "http", "url": url}).Debug(err)
+		// a listener that doesn't speak http is still an open port
+		if atomic.LoadInt32(&connected) == 1 {
+			return open, ""
+		}
+		return closed, ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return open, fmt.Sprintf("redirect %s", resp.Header.Get("Location"))
+	}
+
+	return open, ""
+}
@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/discover"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// maxFingerprintBody is how much of a page we read looking for its title
+const maxFingerprintBody = 64 * 1024
+
+// errNoHTTPFingerprint is returned when neither the redfish service root nor the landing page answered
+var errNoHTTPFingerprint = errors.New("no http fingerprint")
+
+var titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// redfishServiceRoot has the fields of /redfish/v1/ we care about, Vendor and
+// Product only exist since redfish 1.5 so the Oem keys are used as fallback
+type redfishServiceRoot struct {
+	Vendor         string                     `json:"Vendor"`
+	Product        string                     `json:"Product"`
+	RedfishVersion string                     `json:"RedfishVersion"`
+	Oem            map[string]json.RawMessage `json:"Oem"`
+}
+
+// httpHints maps what the web interface tells about itself to the bmclib
+// probe able to talk to it, the first match wins so the more specific
+// patterns come first
+var httpHints = []struct {
+	hint     string
+	patterns []string
+}{
+	{discover.ProbeM1000e, []string{"m1000e", "chassis management controller"}},
+	{discover.ProbeIdrac9, []string{"idrac9", "idrac 9"}},
+	{discover.ProbeIdrac8, []string{"idrac8", "idrac 8"}},
+	{discover.ProbeHpC7000, []string{"onboard administrator"}},
+	{discover.ProbeHpCl100, []string{"cl100"}},
+	{discover.ProbeHpIlo, []string{"integrated lights-out", "ilo 4", "ilo 5", "ilo4", "ilo5"}},
+	{discover.ProbeSupermicrox, []string{"supermicro x10"}},
+	{discover.ProbeSupermicrox11, []string{"supermicro", "aten international"}},
+	{discover.ProbeQuanta, []string{"quanta"}},
+	// redfish capable dell and hpe devices without a more specific match
+	{discover.ProbeIdrac9, []string{"dell"}},
+	{discover.ProbeHpIlo, []string{"hpe"}},
+}
+
+// FingerprintHTTP reads the redfish service root and the landing page of the
+// https port of a BMC, both can be read without authenticating
+func FingerprintHTTP(node string, port int, timeout time.Duration) (*model.HTTPFingerprint, error) {
+	base := fmt.Sprintf("https://%s", net.JoinHostPort(node, strconv.Itoa(port)))
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	fingerprint := &model.HTTPFingerprint{IP: node, Port: port}
+	var found bool
+
+	resp, err := client.Get(base + "/redfish/v1/")
+	if err != nil {
+		log.WithFields(log.Fields{"get": "https", "url": base + "/redfish/v1/"}).Debug(err)
+	} else {
+		found = true
+		fingerprint.Server = resp.Header.Get("Server")
+		if resp.StatusCode == http.StatusOK {
+			parseRedfishServiceRoot(io.LimitReader(resp.Body, maxFingerprintBody), fingerprint)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err = client.Get(base + "/")
+	if err != nil {
+		log.WithFields(log.Fields{"get": "https", "url": base + "/"}).Debug(err)
+	} else {
+		found = true
+		if server := resp.Header.Get("Server"); server != "" {
+			fingerprint.Server = server
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxFingerprintBody))
+		resp.Body.Close()
+		fingerprint.Title = pageTitle(body)
+	}
+
+	if !found {
+		return nil, errNoHTTPFingerprint
+	}
+
+	fingerprint.Hint = discoverHint(fingerprint)
+	return fingerprint, nil
+}
+
+func parseRedfishServiceRoot(body io.Reader, fingerprint *model.HTTPFingerprint) {
+	root := &redfishServiceRoot{}
+	if err := json.NewDecoder(body).Decode(root); err != nil {
+		log.WithFields(log.Fields{"operation": "http fingerprint", "host": fingerprint.IP}).Debug(err)
+		return
+	}
+
+	fingerprint.RedfishVendor = root.Vendor
+	fingerprint.RedfishProduct = root.Product
+	fingerprint.RedfishVersion = root.RedfishVersion
+	if fingerprint.RedfishVendor == "" {
+		for vendor := range root.Oem {
+			fingerprint.RedfishVendor = vendor
+			break
+		}
+	}
+}
+
+// pageTitle returns the content of the title tag of the page with its
+// whitespace collapsed
+func pageTitle(body []byte) string {
+	match := titleRegexp.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+}
+
+// discoverHint returns the bmclib probe matching the fingerprint or an empty
+// string when we can't tell
+func discoverHint(fingerprint *model.HTTPFingerprint) string {
+	seen := strings.ToLower(strings.Join([]string{
+		fingerprint.RedfishVendor,
+		fingerprint.RedfishProduct,
+		fingerprint.Title,
+		fingerprint.Server,
+	}, " "))
+
+	for _, h := range httpHints {
+		for _, pattern := range h.patterns {
+			if strings.Contains(seen, pattern) {
+				return h.hint
+			}
+		}
+	}
+
+	return ""
+}
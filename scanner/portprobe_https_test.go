@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/discover"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestFingerprintHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redfish/v1/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Apache")
+		fmt.Fprint(w, `{"RedfishVersion": "1.6.0", "Vendor": "Dell", "Product": "Integrated Dell Remote Access Controller"}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Apache")
+		fmt.Fprint(w, "<html><head><title>\n  iDRAC9&nbsp;-\n Login</title></head></html>")
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+
+	fingerprint, err := FingerprintHTTP(host, p, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &model.HTTPFingerprint{
+		IP:             host,
+		Port:           p,
+		Server:         "Apache",
+		Title:          "iDRAC9 - Login",
+		RedfishVendor:  "Dell",
+		RedfishProduct: "Integrated Dell Remote Access Controller",
+		RedfishVersion: "1.6.0",
+		Hint:           discover.ProbeIdrac9,
+	}
+	if *fingerprint != *expected {
+		t.Errorf("expected %+v, found %+v", expected, fingerprint)
+	}
+
+	server.Close()
+	if _, err := FingerprintHTTP(host, p, time.Second); err == nil {
+		t.Errorf("expected an error for a closed port")
+	}
+}
+
+func TestDiscoverHint(t *testing.T) {
+	tt := []struct {
+		fingerprint model.HTTPFingerprint
+		hint        string
+	}{
+		{model.HTTPFingerprint{Title: "iDRAC8 - Login"}, discover.ProbeIdrac8},
+		{model.HTTPFingerprint{Title: "PowerEdge M1000e - Chassis Management Controller"}, discover.ProbeM1000e},
+		{model.HTTPFingerprint{RedfishVendor: "Hpe", Title: "iLO 5"}, discover.ProbeHpIlo},
+		{model.HTTPFingerprint{Title: "HP BladeSystem Onboard Administrator"}, discover.ProbeHpC7000},
+		{model.HTTPFingerprint{RedfishVendor: "Supermicro", RedfishProduct: "X10DRW-i"}, discover.ProbeSupermicrox},
+		{model.HTTPFingerprint{RedfishVendor: "Supermicro", RedfishProduct: "X11DPT-B"}, discover.ProbeSupermicrox11},
+		{model.HTTPFingerprint{Title: "Quanta BMC"}, discover.ProbeQuanta},
+		{model.HTTPFingerprint{Server: "lighttpd", Title: "Login"}, ""},
+	}
+
+	for _, tc := range tt {
+		if hint := discoverHint(&tc.fingerprint); hint != tc.hint {
+			t.Errorf("%+v: expected hint %q, found %q", tc.fingerprint, tc.hint, hint)
+		}
+	}
+}
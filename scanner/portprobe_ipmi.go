@@ -0,0 +1,235 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+const (
+	rmcpClassASF  = 0x06
+	rmcpClassIPMI = 0x07
+	asfIANA       = 4542
+	asfPong       = 0x40
+)
+
+var (
+	// errNotAPong is returned when the answer to the presence ping isn't an ASF presence pong
+	errNotAPong = errors.New("not an asf presence pong")
+	// errInvalidIPMIResponse is returned when we fail to parse the get channel authentication capabilities response
+	errInvalidIPMIResponse = errors.New("invalid ipmi response")
+)
+
+// This payload is the rmcp ping from rmcp rfc
+var rmcpPresencePing = []byte("\x06\x00\xff\x06\x00\x00\x11\xbe\x80\x18\x00\x00")
+
+// authTypes are the bits of the authentication type support byte
+var authTypes = []struct {
+	bit  byte
+	name string
+}{
+	{0x01, "none"},
+	{0x02, "md2"},
+	{0x04, "md5"},
+	{0x10, "password"},
+	{0x20, "oem"},
+}
+
+// probeIPMI determines whether the indicated IPMI port on the target host is
+// open, which is only the case when it answers our presence ping with a pong.
+func probeIPMI(node string, port int, timeout time.Duration) (Result, string) {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"dial": "udp", "address": address}).Debug(err)
+		return closed, ""
+	}
+	defer conn.Close()
+
+	pong, err := rmcpExchange(conn, rmcpPresencePing, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"ping": "rmcp", "address": address}).Debug(err)
+		return closed, ""
+	}
+
+	if err = parsePresencePong(pong, &model.IpmiFingerprint{}); err != nil {
+		log.WithFields(log.Fields{"ping": "rmcp", "address": address}).Debug(err)
+		return closed, ""
+	}
+
+	return open, ""
+}
+
+// FingerprintIPMI asks the BMC, without authenticating, which IPMI versions and
+// authentication types it supports and whether anonymous or null user logins
+// are allowed
+func FingerprintIPMI(node string, port int, timeout time.Duration) (fingerprint *model.IpmiFingerprint, err error) {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return fingerprint, err
+	}
+	defer conn.Close()
+
+	fingerprint = &model.IpmiFingerprint{IP: node, Port: port}
+
+	pong, err := rmcpExchange(conn, rmcpPresencePing, timeout)
+	if err != nil {
+		return fingerprint, err
+	}
+	if err = parsePresencePong(pong, fingerprint); err != nil {
+		return fingerprint, err
+	}
+
+	// ask for the ipmi v2.0 extended data first, bmcs only speaking 1.5 answer
+	// that request with an error, so we retry without it
+	var caps []byte
+	for _, channel := range []byte{0x8e, 0x0e} {
+		var response []byte
+		response, err = rmcpExchange(conn, getChannelAuthCapabilities(channel), timeout)
+		if err != nil {
+			continue
+		}
+
+		caps, err = parseChannelAuthCapabilities(response)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fingerprint, err
+	}
+
+	fillAuthCapabilities(caps, fingerprint)
+	return fingerprint, nil
+}
+
+func rmcpExchange(conn net.Conn, payload []byte, timeout time.Duration) (response []byte, err error) {
+	if _, err = conn.Write(payload); err != nil {
+		return response, err
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return response, err
+	}
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return response, err
+	}
+
+	return buf[:n], nil
+}
+
+func parsePresencePong(pong []byte, fingerprint *model.IpmiFingerprint) error {
+	// rmcp header, asf header and the 16 bytes of pong data
+	if len(pong) < 28 || pong[0] != 0x06 || pong[3]&0x7f != rmcpClassASF ||
+		binary.BigEndian.Uint32(pong[4:8]) != asfIANA || pong[8] != asfPong {
+		return errNotAPong
+	}
+
+	fingerprint.IanaEnterprise = int(binary.BigEndian.Uint32(pong[12:16]))
+	fingerprint.IpmiSupported = pong[20]&0x80 != 0
+
+	return nil
+}
+
+// getChannelAuthCapabilities builds a session-less ipmi 1.5 request of the
+// get channel authentication capabilities command for the administrator level
+func getChannelAuthCapabilities(channel byte) []byte {
+	message := []byte{
+		0x20,      // rsAddr, the bmc
+		0x06 << 2, // netFn app, rsLUN 0
+		0x00,      // checksum of the header, set below
+		0x81,      // rqAddr, a remote console
+		0x00,      // rqSeq, rqLUN 0
+		0x38,      // get channel authentication capabilities
+		channel,   // current channel, bit 7 asks for the ipmi v2.0 data
+		0x04,      // administrator
+		0x00,      // checksum of the data, set below
+	}
+	message[2] = ipmiChecksum(message[0:2])
+	message[8] = ipmiChecksum(message[3:8])
+
+	packet := []byte{
+		0x06, 0x00, 0xff, rmcpClassIPMI, // rmcp header, no ack
+		0x00,                   // authentication type none
+		0x00, 0x00, 0x00, 0x00, // session sequence
+		0x00, 0x00, 0x00, 0x00, // session id
+		byte(len(message)),
+	}
+
+	return append(packet, message...)
+}
+
+// parseChannelAuthCapabilities returns the data of a successful get channel
+// authentication capabilities response
+func parseChannelAuthCapabilities(response []byte) ([]byte, error) {
+	if len(response) < 14 || response[3]&0x7f != rmcpClassIPMI {
+		return nil, errInvalidIPMIResponse
+	}
+
+	offset := 13
+	if response[4] != 0x00 {
+		// authentication code of authenticated sessions
+		offset += 16
+	}
+	if len(response) < offset+1 {
+		return nil, errInvalidIPMIResponse
+	}
+
+	length := int(response[offset])
+	message := response[offset+1:]
+	// header (6), completion code, 8 bytes of data and the checksum
+	if length < 16 || len(message) < length || message[5] != 0x38 {
+		return nil, errInvalidIPMIResponse
+	}
+
+	if message[6] != 0x00 {
+		return nil, errInvalidIPMIResponse
+	}
+
+	return message[7:15], nil
+}
+
+func fillAuthCapabilities(caps []byte, fingerprint *model.IpmiFingerprint) {
+	var supported []string
+	for _, authType := range authTypes {
+		if caps[1]&authType.bit != 0 {
+			supported = append(supported, authType.name)
+		}
+	}
+	fingerprint.AuthTypes = strings.Join(supported, ",")
+
+	fingerprint.AnonymousLogin = caps[2]&0x01 != 0
+	fingerprint.NullUsernames = caps[2]&0x02 != 0
+	fingerprint.NonNullUsernames = caps[2]&0x04 != 0
+
+	versions := []string{"1.5"}
+	if caps[1]&0x80 != 0 {
+		versions = nil
+		if caps[3]&0x01 != 0 {
+			versions = append(versions, "1.5")
+		}
+		if caps[3]&0x02 != 0 {
+			versions = append(versions, "2.0")
+		}
+	}
+	fingerprint.IpmiVersion = strings.Join(versions, ",")
+}
+
+func ipmiChecksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return -sum
+}
@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBMC answers the rmcp presence ping and the get channel authentication
+// capabilities request like a BMC allowing ipmi 1.5 anonymous logins would
+func fakeBMC(t *testing.T, extended bool) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			switch buf[3] {
+			case rmcpClassASF:
+				conn.WriteTo([]byte{
+					0x06, 0x00, 0xff, 0x06, // rmcp
+					0x00, 0x00, 0x11, 0xbe, asfPong, 0x00, 0x00, 0x10, // asf
+					0x00, 0x00, 0x11, 0xbe, 0x00, 0x00, 0x00, 0x00, 0x81, 0x00, // pong data
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				}, addr)
+			case rmcpClassIPMI:
+				channel := buf[n-3]
+				if channel&0x80 != 0 && !extended {
+					// invalid data field in request
+					message := []byte{0x81, 0x1c, 0x00, 0x20, 0x00, 0x38, 0xcc, 0x00}
+					conn.WriteTo(append([]byte{0x06, 0x00, 0xff, 0x07, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, byte(len(message))}, message...), addr)
+					continue
+				}
+
+				caps := []byte{0x01, 0x15, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}
+				if extended {
+					caps = []byte{0x01, 0x95, 0x06, 0x03, 0x00, 0x00, 0x00, 0x00}
+				}
+				message := append([]byte{0x81, 0x1c, 0x63, 0x20, 0x00, 0x38, 0x00}, caps...)
+				message = append(message, ipmiChecksum(message[3:]))
+				conn.WriteTo(append([]byte{0x06, 0x00, 0xff, 0x07, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, byte(len(message))}, message...), addr)
+			}
+		}
+	}()
+
+	return conn
+}
+
+func TestFingerprintIPMI(t *testing.T) {
+	tt := []struct {
+		extended       bool
+		version        string
+		authTypes      string
+		anonymousLogin bool
+		nullUsernames  bool
+	}{
+		{false, "1.5", "none,md5,password", true, false},
+		{true, "1.5,2.0", "none,md5,password", false, true},
+	}
+
+	for _, tc := range tt {
+		conn := fakeBMC(t, tc.extended)
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+
+		if result, _ := probeIPMI("127.0.0.1", port, time.Second); result != open {
+			t.Errorf("expected the ipmi port to be open, found %s", result)
+		}
+
+		fingerprint, err := FingerprintIPMI("127.0.0.1", port, time.Second)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !fingerprint.IpmiSupported || fingerprint.IanaEnterprise != asfIANA {
+			t.Errorf("unexpected presence pong data %+v", fingerprint)
+		}
+		if fingerprint.IpmiVersion != tc.version || fingerprint.AuthTypes != tc.authTypes ||
+			fingerprint.AnonymousLogin != tc.anonymousLogin || fingerprint.NullUsernames != tc.nullUsernames {
+			t.Errorf("unexpected fingerprint %+v", fingerprint)
+		}
+	}
+}
+
+func TestProbeIPMIWithoutPong(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	if result, _ := probeIPMI("127.0.0.1", port, 200*time.Millisecond); result != closed {
+		t.Errorf("expected the ipmi port to be closed, found %s", result)
+	}
+}
@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// probeKVM determines whether the remote console (KVM) port on the target host
+// is open. Most BMCs speak RFB there, in that case the protocol version
+// announced by the server is returned as details
+func probeKVM(node string, port int, timeout time.Duration) (Result, string) {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"dial": "tcp", "address": address}).Debug(err)
+		return closed, ""
+	}
+	defer conn.Close()
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		log.WithFields(log.Fields{"set read timeout": "tcp", "address": address}).Debug(err)
+		return open, ""
+	}
+
+	// RFB servers talk first: "RFB 003.008\n"
+	buf := make([]byte, 12)
+	n, err := conn.Read(buf)
+	if err != nil {
+		log.WithFields(log.Fields{"read": "tcp", "address": address}).Debug(err)
+		return open, ""
+	}
+
+	if bytes.HasPrefix(buf[:n], []byte("RFB ")) {
+		return open, string(bytes.TrimSpace(buf[:n]))
+	}
+
+	return open, ""
+}
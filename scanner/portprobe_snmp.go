@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// BER tags used by the snmp messages we send and read
+const (
+	berInteger      = 0x02
+	berOctetString  = 0x04
+	berNull         = 0x05
+	berOID          = 0x06
+	berSequence     = 0x30
+	snmpGetRequest  = 0xa0
+	snmpGetResponse = 0xa2
+)
+
+// sysDescr.0
+var snmpSysDescrOID = []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}
+
+var errInvalidBER = errors.New("invalid ber encoding")
+
+// probeSNMP determines whether an snmp agent answers on the indicated udp port
+// of the target host, sending a v2c GET of sysDescr.0 with the community from
+// scanner.snmp_community. The sysDescr is returned as details
+func probeSNMP(node string, port int, timeout time.Duration) (Result, string) {
+	address := net.JoinHostPort(node, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"dial": "udp", "address": address}).Debug(err)
+		return closed, ""
+	}
+	defer conn.Close()
+
+	requestID := rand.Int31()
+	_, err = conn.Write(snmpGetSysDescr(viper.GetString("scanner.snmp_community"), requestID))
+	if err != nil {
+		log.WithFields(log.Fields{"write": "udp", "address": address}).Debug(err)
+		return closed, ""
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		log.WithFields(log.Fields{"set read timeout": "udp", "address": address}).Debug(err)
+		return closed, ""
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		log.WithFields(log.Fields{"read": "udp", "address": address}).Debug(err)
+		return closed, ""
+	}
+
+	sysDescr, err := snmpParseSysDescr(buf[:n], requestID)
+	if err != nil {
+		// the agent answered, even if we don't understand it
+		log.WithFields(log.Fields{"parse": "snmp", "address": address}).Debug(err)
+	}
+
+	return open, sysDescr
+}
+
+func berLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+
+	var b []byte
+	for ; length > 0; length >>= 8 {
+		b = append([]byte{byte(length)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berTLV(tag byte, value ...[]byte) []byte {
+	var content []byte
+	for _, v := range value {
+		content = append(content, v...)
+	}
+
+	tlv := append([]byte{tag}, berLength(len(content))...)
+	return append(tlv, content...)
+}
+
+func berInt(i int32) []byte {
+	b := []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+	// strip the redundant leading bytes, keeping the sign bit right
+	for len(b) > 1 && ((b[0] == 0x00 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+	return berTLV(berInteger, b)
+}
+
+// berRead splits the first TLV of buf, returning its tag, value and what follows it
+func berRead(buf []byte) (tag byte, value []byte, rest []byte, err error) {
+	if len(buf) < 2 {
+		return tag, value, rest, errInvalidBER
+	}
+
+	tag = buf[0]
+	length := int(buf[1])
+	offset := 2
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 4 || len(buf) < offset+size {
+			return tag, value, rest, errInvalidBER
+		}
+		length = 0
+		for _, b := range buf[offset : offset+size] {
+			length = length<<8 | int(b)
+		}
+		offset += size
+	}
+
+	if length < 0 || len(buf) < offset+length {
+		return tag, value, rest, errInvalidBER
+	}
+
+	return tag, buf[offset : offset+length], buf[offset+length:], nil
+}
+
+func berReadInt(buf []byte) (i int64, rest []byte, err error) {
+	tag, value, rest, err := berRead(buf)
+	if err != nil {
+		return i, rest, err
+	}
+	if tag != berInteger || len(value) == 0 || len(value) > 8 {
+		return i, rest, errInvalidBER
+	}
+
+	i = int64(int8(value[0]))
+	for _, b := range value[1:] {
+		i = i<<8 | int64(b)
+	}
+	return i, rest, nil
+}
+
+func snmpGetSysDescr(community string, requestID int32) []byte {
+	varBind := berTLV(berSequence, berTLV(berOID, snmpSysDescrOID), berTLV(berNull))
+	pdu := berTLV(snmpGetRequest,
+		berInt(requestID),
+		berInt(0), // error-status
+		berInt(0), // error-index
+		berTLV(berSequence, varBind),
+	)
+
+	// version 1 is snmp v2c
+	return berTLV(berSequence, berInt(1), berTLV(berOctetString, []byte(community)), pdu)
+}
+
+func snmpParseSysDescr(buf []byte, requestID int32) (sysDescr string, err error) {
+	tag, message, _, err := berRead(buf)
+	if err != nil || tag != berSequence {
+		return sysDescr, errInvalidBER
+	}
+
+	// version and community
+	if _, message, err = berReadInt(message); err != nil {
+		return sysDescr, err
+	}
+	if _, _, message, err = berRead(message); err != nil {
+		return sysDescr, err
+	}
+
+	tag, pdu, _, err := berRead(message)
+	if err != nil || tag != snmpGetResponse {
+		return sysDescr, errInvalidBER
+	}
+
+	id, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return sysDescr, err
+	}
+	if id != int64(requestID) {
+		return sysDescr, errors.New("snmp response for another request")
+	}
+
+	errorStatus, pdu, err := berReadInt(pdu)
+	if err != nil {
+		return sysDescr, err
+	}
+	if errorStatus != 0 {
+		return sysDescr, errors.New("snmp error status " + strconv.FormatInt(errorStatus, 10))
+	}
+
+	// error-index, then the varbind list with a single varbind
+	if _, pdu, err = berReadInt(pdu); err != nil {
+		return sysDescr, err
+	}
+	if _, pdu, _, err = berRead(pdu); err != nil {
+		return sysDescr, err
+	}
+	if _, pdu, _, err = berRead(pdu); err != nil {
+		return sysDescr, err
+	}
+	if _, _, pdu, err = berRead(pdu); err != nil {
+		return sysDescr, err
+	}
+
+	tag, value, _, err := berRead(pdu)
+	if err != nil {
+		return sysDescr, err
+	}
+	if tag != berOctetString {
+		// noSuchObject and friends
+		return sysDescr, errors.New("sysDescr not available")
+	}
+
+	return string(value), nil
+}
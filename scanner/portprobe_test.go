@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestProbeSNMP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		// answer with the request id we received
+		_, message, _, _ := berRead(buf[:n])
+		_, message, _ = berReadInt(message)
+		_, _, message, _ = berRead(message)
+		_, pdu, _, _ := berRead(message)
+		requestID, _, _ := berReadInt(pdu)
+
+		varBind := berTLV(berSequence, berTLV(berOID, snmpSysDescrOID), berTLV(berOctetString, []byte("Linux bmc 4.4")))
+		response := berTLV(berSequence, berInt(1), berTLV(berOctetString, []byte("public")), berTLV(snmpGetResponse,
+			berInt(int32(requestID)), berInt(0), berInt(0), berTLV(berSequence, varBind)))
+		conn.WriteTo(response, addr)
+	}()
+
+	viper.SetDefault("scanner.snmp_community", "public")
+	result, details := probeSNMP("127.0.0.1", conn.LocalAddr().(*net.UDPAddr).Port, time.Second)
+	if result != open || details != "Linux bmc 4.4" {
+		t.Errorf("expected open with sysDescr, found %s %q", result, details)
+	}
+}
+
+func TestBerInt(t *testing.T) {
+	for _, i := range []int32{0, 1, 127, 128, 255, 256, -1, -129, 2147483647} {
+		v, rest, err := berReadInt(berInt(i))
+		if err != nil || len(rest) != 0 || v != int64(i) {
+			t.Errorf("%d: found %d %v", i, v, err)
+		}
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"lenovo": []map[string]interface{}{
+			{"protocol": "tcp", "port": 8443, "timeout": "2s", "retries": 2, "https": true},
+			{"protocol": "tcp", "port": 443},
+		},
+	})
+	viper.Set("scanner.sites.ams4.profile", "lenovo")
+	defer viper.Set("scanner.profiles", nil)
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(profiles[DefaultProfile]) != 3 {
+		t.Errorf("expected the builtin default profile, found %+v", profiles[DefaultProfile])
+	}
+
+	lenovo := profiles["lenovo"]
+	if len(lenovo) != 2 || lenovo[0].Port != 8443 || lenovo[0].Timeout != 2*time.Second || lenovo[0].Retries != 2 || !lenovo[0].HTTPS {
+		t.Errorf("unexpected lenovo profile %+v", lenovo)
+	}
+	if len(lenovo) == 2 && !lenovo[1].HTTPS {
+		t.Errorf("expected tcp/443 to always be https, found %+v", lenovo[1])
+	}
+
+	if name := profileName(&ToScan{Site: "ams4"}); name != "lenovo" {
+		t.Errorf("expected the site profile, found %s", name)
+	}
+	if name := profileName(&ToScan{Site: "ams4", Profile: DefaultProfile}); name != DefaultProfile {
+		t.Errorf("expected the requested profile, found %s", name)
+	}
+
+	viper.Set("scanner.timeout", 3*time.Second)
+	defer viper.Set("scanner.timeout", nil)
+	if profiles, err = LoadProfiles(); err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range profiles[DefaultProfile] {
+		if option.Timeout != 3*time.Second {
+			t.Errorf("expected the builtin default profile to follow scanner.timeout, found %+v", option)
+		}
+	}
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"broken": []map[string]interface{}{{"protocol": "gopher", "port": 70}},
+	})
+	if _, err := LoadProfiles(); err == nil {
+		t.Errorf("expected an error for an unsupported protocol")
+	}
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"broken": []map[string]interface{}{{"protocol": "ipmi", "port": 623, "https": true}},
+	})
+	if _, err := LoadProfiles(); err == nil {
+		t.Errorf("expected an error for https on another protocol than tcp")
+	}
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// errNoCertificate is returned when the tls handshake succeeds without a peer certificate
+var errNoCertificate = errors.New("no certificate presented")
+
+// InspectCertificate connects to the tls port of the node and returns the
+// leaf certificate it presents, the chain isn't verified since most BMCs use
+// self-signed certificates
+func InspectCertificate(node string, port int, timeout time.Duration) (*model.Certificate, error) {
+	conn, err := tls.DialWithDialer(
+		&net.Dialer{Timeout: timeout},
+		"tcp",
+		net.JoinHostPort(node, strconv.Itoa(port)),
+		&tls.Config{InsecureSkipVerify: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	peers := conn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, errNoCertificate
+	}
+
+	certificate := newCertificate(peers[0])
+	certificate.IP = node
+	certificate.Port = port
+	return certificate, nil
+}
+
+func newCertificate(cert *x509.Certificate) *model.Certificate {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+
+	keyType, keySize := publicKeyInfo(cert.PublicKey)
+
+	return &model.Certificate{
+		Subject:           cert.Subject.String(),
+		Sans:              strings.Join(sans, ","),
+		Issuer:            cert.Issuer.String(),
+		Serial:            fmt.Sprintf("%x", cert.SerialNumber),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		KeyType:           keyType,
+		KeySize:           keySize,
+		SelfSigned:        isSelfSigned(cert),
+		FingerprintSha256: fmt.Sprintf("%x", sha256.Sum256(cert.Raw)),
+	}
+}
+
+func publicKeyInfo(key interface{}) (keyType string, keySize int) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "rsa", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ecdsa", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "ed25519", 256
+	default:
+		return "unknown", 0
+	}
+}
+
+// isSelfSigned tells whether the certificate is its own issuer: the subject
+// and the issuer are the same and so are its key ids, when it has them.
+// Without key ids the signature tells, unless crypto/x509 refuses to check its
+// algorithm, like the MD5 based ones older BMCs still use. CheckSignatureFrom
+// can't be used as BMC certificates are rarely marked as CA
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) != 0 && len(cert.SubjectKeyId) != 0 {
+		return bytes.Equal(cert.AuthorityKeyId, cert.SubjectKeyId)
+	}
+
+	err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+	var insecure x509.InsecureAlgorithmError
+	return err == nil || errors.As(err, &insecure) || errors.Is(err, x509.ErrUnsupportedAlgorithm)
+}
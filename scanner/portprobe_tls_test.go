@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspectCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+
+	certificate, err := InspectCertificate(host, p, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf := server.Certificate()
+	if certificate.IP != host || certificate.Port != p {
+		t.Errorf("expected %s:%d, found %s:%d", host, p, certificate.IP, certificate.Port)
+	}
+	if certificate.Subject != leaf.Subject.String() || certificate.Issuer != leaf.Issuer.String() {
+		t.Errorf("unexpected subject %q or issuer %q", certificate.Subject, certificate.Issuer)
+	}
+	if !certificate.NotAfter.Equal(leaf.NotAfter) || !certificate.NotBefore.Equal(leaf.NotBefore) {
+		t.Errorf("unexpected validity %s - %s", certificate.NotBefore, certificate.NotAfter)
+	}
+	if !strings.Contains(certificate.Sans, "example.com") || !strings.Contains(certificate.Sans, "127.0.0.1") {
+		t.Errorf("unexpected sans %q", certificate.Sans)
+	}
+	if certificate.KeyType == "unknown" || certificate.KeySize == 0 {
+		t.Errorf("unexpected key %s/%d", certificate.KeyType, certificate.KeySize)
+	}
+	if !certificate.SelfSigned {
+		t.Errorf("expected the httptest certificate to be self-signed")
+	}
+	if len(certificate.FingerprintSha256) != 64 {
+		t.Errorf("unexpected fingerprint %q", certificate.FingerprintSha256)
+	}
+
+	server.Close()
+	if _, err := InspectCertificate(host, p, time.Second); err == nil {
+		t.Errorf("expected an error for a closed port")
+	}
+}
+
+func TestIsSelfSigned(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate := func(authorityKeyID []byte, subjectKeyID []byte) *x509.Certificate {
+		template := &x509.Certificate{
+			SerialNumber:   big.NewInt(1),
+			Subject:        pkix.Name{CommonName: "bmc"},
+			NotBefore:      time.Now(),
+			NotAfter:       time.Now().Add(time.Hour),
+			AuthorityKeyId: authorityKeyID,
+			SubjectKeyId:   subjectKeyID,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	if cert := certificate(nil, nil); !isSelfSigned(cert) {
+		t.Errorf("expected a certificate signed by its own key to be self-signed")
+	}
+
+	// crypto/x509 doesn't check md5 signatures, the names still tell
+	cert := certificate(nil, nil)
+	cert.SignatureAlgorithm = x509.MD5WithRSA
+	if !isSelfSigned(cert) {
+		t.Errorf("expected an md5 certificate issued by itself to be self-signed")
+	}
+
+	cert = certificate(nil, nil)
+	cert.Signature = cert.Signature[:len(cert.Signature)-1]
+	if isSelfSigned(cert) {
+		t.Errorf("expected a certificate not signed by its own key not to be self-signed")
+	}
+
+	cert = certificate([]byte{1, 2, 3}, []byte{1, 2, 3})
+	cert.Signature = cert.Signature[:len(cert.Signature)-1]
+	if !isSelfSigned(cert) {
+		t.Errorf("expected matching key ids to be self-signed")
+	}
+
+	if cert := certificate([]byte{4, 5, 6}, []byte{1, 2, 3}); isSelfSigned(cert) {
+		t.Errorf("expected a certificate issued by another key of the same name not to be self-signed")
+	}
+}
@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is the name of the profile used when neither the site nor
+// the scan request ask for a specific one
+const DefaultProfile = "default"
+
+// ScanOption is a single probe of a scan profile
+type ScanOption struct {
+	Protocol string        `mapstructure:"protocol"`
+	Port     int           `mapstructure:"port"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	Retries  int           `mapstructure:"retries"`
+	// HTTPS gets the open port fingerprinted and its certificate inspected,
+	// tcp/443 always is
+	HTTPS bool `mapstructure:"https"`
+}
+
+// defaultScanProfile is what dora always scanned before profiles were
+// configurable, its probes wait for scanner.timeout
+func defaultScanProfile() []*ScanOption {
+	return []*ScanOption{
+		{
+			Protocol: "tcp",
+			Port:     22,
+		},
+		{
+			Protocol: "tcp",
+			Port:     443,
+			HTTPS:    true,
+		},
+		{
+			Protocol: "ipmi",
+			Port:     623,
+		},
+	}
+}
+
+// LoadProfiles reads scanner.profiles from the config, a profile named
+// default is always present
+func LoadProfiles() (profiles map[string][]*ScanOption, err error) {
+	profiles = make(map[string][]*ScanOption)
+	if err = viper.UnmarshalKey("scanner.profiles", &profiles); err != nil {
+		return profiles, err
+	}
+
+	if _, ok := profiles[DefaultProfile]; !ok {
+		profiles[DefaultProfile] = defaultScanProfile()
+	}
+
+	for name, profile := range profiles {
+		if len(profile) == 0 {
+			return profiles, fmt.Errorf("scan profile %s has no probes", name)
+		}
+
+		for _, option := range profile {
+			if !IsSupportedProtocol(option.Protocol) {
+				return profiles, fmt.Errorf("scan profile %s: %w: %s", name, ErrUnsupportedProtocol, option.Protocol)
+			}
+			if option.Port <= 0 || option.Port > 65535 {
+				return profiles, fmt.Errorf("scan profile %s: invalid port %d", name, option.Port)
+			}
+			if option.HTTPS && option.Protocol != "tcp" {
+				return profiles, fmt.Errorf("scan profile %s: https only applies to tcp, found %s", name, option.Protocol)
+			}
+			if option.Timeout <= 0 {
+				option.Timeout = viper.GetDuration("scanner.timeout")
+			}
+			if option.Retries < 0 {
+				option.Retries = 0
+			}
+			if option.Protocol == "tcp" && option.Port == 443 {
+				option.HTTPS = true
+			}
+		}
+	}
+
+	return profiles, nil
+}
+
+// profileName returns the profile to be used for the subnet: the one asked by
+// the scan request, the one of the site or the default one, in that order
+func profileName(subnet *ToScan) string {
+	if subnet.Profile != "" {
+		return subnet.Profile
+	}
+
+	if profile := viper.GetString(fmt.Sprintf("scanner.sites.%s.profile", subnet.Site)); profile != "" {
+		return profile
+	}
+
+	return DefaultProfile
+}
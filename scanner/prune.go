@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// ErrNoSubnets is returned when the subnet source returns nothing, which is
+// far more likely a broken source than every network being gone
+var ErrNoSubnets = errors.New("subnet source returned no subnets, refusing to remove scanned ports")
+
+// PruneResult counts the scanned ports touched by Prune
+type PruneResult struct {
+	Stale   int64
+	Removed int64
+}
+
+// Prune applies the retention policy to the scanned ports: ports not
+// refreshed by the latest scan of their network are marked as stale and
+// ports of networks no longer returned by the subnet source are removed,
+// unless the network was scanned ad hoc within scanner.adhoc_retention. With
+// dryRun nothing is changed, only counted
+func Prune(db *gorm.DB, dryRun bool) (result PruneResult, err error) {
+	cidrs, err := sourceCIDRs()
+	if err != nil {
+		return result, err
+	}
+
+	tx := db.Begin()
+	adHoc, err := adHocCIDRs(tx)
+	if err != nil {
+		tx.Rollback()
+		return result, err
+	}
+
+	if result.Stale, err = MarkStale(tx, append(cidrs, adHoc...)); err != nil {
+		tx.Rollback()
+		return result, err
+	}
+
+	if result.Removed, err = RemoveMissingSubnets(tx, append(cidrs, adHoc...)); err != nil {
+		tx.Rollback()
+		return result, err
+	}
+
+	if dryRun {
+		return result, tx.Rollback().Error
+	}
+	return result, tx.Commit().Error
+}
+
+// sourceCIDRs returns all the networks known by the configured subnet source
+func sourceCIDRs() (cidrs []string, err error) {
+	subnets, err := ListSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		return cidrs, err
+	}
+
+	for _, subnet := range subnets {
+		cidrs = append(cidrs, subnet.CIDR)
+	}
+	return cidrs, nil
+}
+
+// adHocCIDRs returns the networks scanned ad hoc within scanner.adhoc_retention,
+// they are pruned like the networks gone from the source once it's over
+func adHocCIDRs(db *gorm.DB) (cidrs []string, err error) {
+	since := time.Now().Add(-viper.GetDuration("scanner.adhoc_retention"))
+	err = db.Model(&model.ScanRunSubnet{}).Where("ad_hoc = ? and updated_at > ?", true, since).Pluck("distinct cidr", &cidrs).Error
+	return cidrs, err
+}
+
+// MarkStale marks the scanned ports of the given networks that weren't
+// refreshed by the latest finished scan of the network as stale. Each
+// scanner keeps its own rows, so the latest scan is looked up per scanned_by
+func MarkStale(db *gorm.DB, cidrs []string) (stale int64, err error) {
+	for _, cidr := range cidrs {
+		var scans []model.ScanRunSubnet
+		if err = db.Select("scanned_by, started_at").Where("cidr = ? and state = ?", cidr, model.ScanRunFinished).Order("started_at desc").Find(&scans).Error; err != nil {
+			return stale, err
+		}
+
+		latest := make(map[string]time.Time)
+		for _, scan := range scans {
+			if _, ok := latest[scan.ScannedBy]; ok || scan.StartedAt == nil {
+				continue
+			}
+			latest[scan.ScannedBy] = *scan.StartedAt
+		}
+
+		for scannedBy, startedAt := range latest {
+			q := db.Model(&model.ScannedPort{}).Where("cidr = ? and scanned_by = ? and updated_at < ? and stale = ?", cidr, scannedBy, startedAt, false).UpdateColumn("stale", true)
+			if q.Error != nil {
+				return stale, q.Error
+			}
+			stale += q.RowsAffected
+		}
+	}
+
+	return stale, nil
+}
+
+// RemoveMissingSubnets removes the scanned ports, with their fingerprints and
+// certificates, of the networks that aren't in cidrs anymore
+func RemoveMissingSubnets(db *gorm.DB, cidrs []string) (removed int64, err error) {
+	if len(cidrs) == 0 {
+		return removed, ErrNoSubnets
+	}
+
+	missing := db.Model(&model.ScannedPort{}).Select("id").Where("cidr not in (?)", cidrs).QueryExpr()
+	for _, child := range []interface{}{&model.IpmiFingerprint{}, &model.HTTPFingerprint{}, &model.Certificate{}} {
+		if err = db.Where("scanned_port_id in (?)", missing).Delete(child).Error; err != nil {
+			return removed, err
+		}
+	}
+
+	q := db.Where("cidr not in (?)", cidrs).Delete(&model.ScannedPort{})
+	return q.RowsAffected, q.Error
+}
+
+// pruneAfterScan marks the ports of the freshly scanned network that didn't
+// answer this time as stale when scanner.prune_after_scan is set
+func pruneAfterScan(db *gorm.DB, result *model.ScanRunSubnet) {
+	if !viper.GetBool("scanner.prune_after_scan") || result.State != model.ScanRunFinished || result.ScanRunID == "" {
+		return
+	}
+
+	stale, err := MarkStale(db, []string{result.CIDR})
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "pruning", "subnet": result.CIDR}).Error(err)
+		return
+	}
+	if stale > 0 {
+		log.WithFields(log.Fields{"operation": "pruning", "subnet": result.CIDR, "stale": stale}).Info("scanned ports marked as stale")
+	}
+}
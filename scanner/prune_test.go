@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestPrune(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	scanStart := time.Now().Add(-time.Hour)
+	db.Create(&model.ScanRunSubnet{ScanRunID: "run", CIDR: "192.168.64.0/24", ScannedBy: "anomalia", State: model.ScanRunFinished, StartedAt: &scanStart})
+
+	ports := []*model.ScannedPort{
+		{IP: "192.168.64.10", CIDR: "192.168.64.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+		{IP: "192.168.64.11", CIDR: "192.168.64.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+		{IP: "192.168.64.12", CIDR: "192.168.64.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "other"},
+		{IP: "192.168.99.10", CIDR: "192.168.99.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+	}
+	for _, port := range ports {
+		if err := db.Create(port).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not refreshed by the latest scan, "other" never finished a scan of the network
+	db.Model(ports[1]).UpdateColumn("updated_at", scanStart.Add(-time.Minute))
+	db.Model(ports[2]).UpdateColumn("updated_at", scanStart.Add(-time.Minute))
+	db.Create(&model.HTTPFingerprint{ScannedPortID: ports[3].ID, IP: ports[3].IP, Port: 443})
+
+	stale, err := MarkStale(db, []string{"192.168.64.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale != 1 {
+		t.Errorf("expected 1 stale port, found %d", stale)
+	}
+
+	var stalePorts []model.ScannedPort
+	db.Where("stale = ?", true).Find(&stalePorts)
+	if len(stalePorts) != 1 || stalePorts[0].IP != "192.168.64.11" {
+		t.Errorf("expected 192.168.64.11 to be stale, found %+v", stalePorts)
+	}
+
+	removed, err := RemoveMissingSubnets(db, []string{"192.168.64.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed port, found %d", removed)
+	}
+
+	var count int
+	db.Model(&model.HTTPFingerprint{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the fingerprints of removed ports to be gone, found %d", count)
+	}
+
+	if _, err := RemoveMissingSubnets(db, nil); err != ErrNoSubnets {
+		t.Errorf("expected ErrNoSubnets, found %v", err)
+	}
+}
+
+func TestPruneKeepsAdHocNetworks(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	dir, err := ioutil.TempDir("", "dora-subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "subnets.yaml")
+	if err := ioutil.WriteFile(file, []byte("subnets:\n  - cidr: 192.168.64.0/24\n    site: adc1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("scanner.subnet_source", "file")
+	viper.Set("scanner.subnet_file", file)
+	viper.Set("scanner.adhoc_retention", 24*time.Hour)
+	defer func() {
+		viper.Set("scanner.subnet_source", nil)
+		viper.Set("scanner.subnet_file", nil)
+		viper.Set("scanner.adhoc_retention", nil)
+	}()
+
+	if _, err := NewScanRun(db, TriggeredByCLI, []*ToScan{{CIDR: "10.20.0.0/24", Site: "adc1", AdHoc: true}}); err != nil {
+		t.Fatal(err)
+	}
+	// scanned ad hoc before the retention
+	old, err := NewScanRun(db, TriggeredByCLI, []*ToScan{{CIDR: "10.30.0.0/24", Site: "adc1", AdHoc: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&model.ScanRunSubnet{}).Where("scan_run_id = ?", old.ID).UpdateColumn("updated_at", time.Now().Add(-48*time.Hour))
+
+	for _, port := range []*model.ScannedPort{
+		{IP: "192.168.64.10", CIDR: "192.168.64.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+		{IP: "10.20.0.10", CIDR: "10.20.0.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+		{IP: "10.30.0.10", CIDR: "10.30.0.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+		{IP: "192.168.99.10", CIDR: "192.168.99.0/24", Port: 443, Protocol: "tcp", State: "open", ScannedBy: "anomalia"},
+	} {
+		if err := db.Create(port).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Prune(db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 2 {
+		t.Errorf("expected the ports of the old ad hoc network and of the missing one to be removed, found %+v", result)
+	}
+
+	var ports []model.ScannedPort
+	db.Order("ip").Find(&ports)
+	if len(ports) != 2 || ports[0].CIDR != "10.20.0.0/24" || ports[1].CIDR != "192.168.64.0/24" {
+		t.Errorf("expected the ports of the recent ad hoc network to survive the prune, found %+v", ports)
+	}
+}
@@ -0,0 +1,279 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/spf13/viper"
+)
+
+// RateLimit is how polite the scanner is towards a site or a subnet, the
+// zero value doesn't limit anything
+type RateLimit struct {
+	// ProbesPerSecond is shared by all the subnets of the site, or by the
+	// subnet alone when it has a limit of its own
+	ProbesPerSecond float64 `mapstructure:"probes_per_second"`
+	// ConnectionsPer24 bounds the hosts probed at the same time in a /24
+	ConnectionsPer24 int `mapstructure:"connections_per_24"`
+	// QuietHours is a HH:MM-HH:MM window of local time without any probe
+	QuietHours string `mapstructure:"quiet_hours"`
+}
+
+// SubnetRateLimit is the rate limit of a single network, they are listed
+// instead of keyed by cidr as viper splits keys on dots
+type SubnetRateLimit struct {
+	CIDR      string `mapstructure:"cidr"`
+	RateLimit `mapstructure:",squash"`
+}
+
+// loadRateLimit returns the rate limit of the subnet and the key of the
+// bucket it draws its probes from: the one listed for the subnet in
+// scanner.subnet_rate_limits, the one of its site or scanner.rate_limit
+func loadRateLimit(subnet *ToScan) (key string, limit RateLimit, err error) {
+	var subnetLimits []SubnetRateLimit
+	if err = viper.UnmarshalKey("scanner.subnet_rate_limits", &subnetLimits); err != nil {
+		return key, limit, err
+	}
+
+	for _, subnetLimit := range subnetLimits {
+		_, ipnet, err := net.ParseCIDR(subnetLimit.CIDR)
+		if err != nil {
+			return key, limit, fmt.Errorf("scanner.subnet_rate_limits: %w", err)
+		}
+		if ipnet.String() == subnet.CIDR {
+			return "subnet " + subnet.CIDR, subnetLimit.RateLimit, nil
+		}
+	}
+
+	siteKey := fmt.Sprintf("scanner.sites.%s.rate_limit", subnet.Site)
+	if !viper.IsSet(siteKey) {
+		siteKey = "scanner.rate_limit"
+	}
+	err = viper.UnmarshalKey(siteKey, &limit)
+	return "site " + subnet.Site, limit, err
+}
+
+// quietHours is a daily window, it may wrap around midnight
+type quietHours struct {
+	start, end time.Duration
+}
+
+func parseQuietHours(window string) (*quietHours, error) {
+	var startH, startM, endH, endM int
+	if _, err := fmt.Sscanf(window, "%d:%d-%d:%d", &startH, &startM, &endH, &endM); err != nil {
+		return nil, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", window)
+	}
+	for _, v := range []int{startH, endH} {
+		if v < 0 || v > 23 {
+			return nil, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", window)
+		}
+	}
+	for _, v := range []int{startM, endM} {
+		if v < 0 || v > 59 {
+			return nil, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", window)
+		}
+	}
+
+	return &quietHours{
+		start: time.Duration(startH)*time.Hour + time.Duration(startM)*time.Minute,
+		end:   time.Duration(endH)*time.Hour + time.Duration(endM)*time.Minute,
+	}, nil
+}
+
+// remaining returns how long the quiet hours still last at now, zero outside of them
+func (q *quietHours) remaining(now time.Time) time.Duration {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sinceMidnight := now.Sub(midnight)
+
+	switch {
+	case q.start < q.end && sinceMidnight >= q.start && sinceMidnight < q.end:
+		return q.end - sinceMidnight
+	case q.start > q.end && sinceMidnight >= q.start:
+		return 24*time.Hour - sinceMidnight + q.end
+	case q.start > q.end && sinceMidnight < q.end:
+		return q.end - sinceMidnight
+	}
+	return 0
+}
+
+// bucket hands out probes at a steady rate, a probe that can't be served
+// right away reserves its slot and waits for it
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64) *bucket {
+	return &bucket{rate: rate, tokens: 1, last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// limiter holds the probe buckets and the connection slots of every /24,
+// it is shared by all the subnets scanned by a pipeline
+type limiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	connections map[string]chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		buckets:     make(map[string]*bucket),
+		connections: make(map[string]chan struct{}),
+	}
+}
+
+// politeness applies the rate limit of a subnet to its probes
+type politeness struct {
+	limiter *limiter
+	key     string
+	limit   RateLimit
+	quiet   *quietHours
+}
+
+// politeness returns the rate limit to apply to the probes of the subnet
+func (l *limiter) politeness(subnet *ToScan) (*politeness, error) {
+	key, limit, err := loadRateLimit(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &politeness{limiter: l, key: key, limit: limit}
+	if limit.QuietHours != "" {
+		if p.quiet, err = parseQuietHours(limit.QuietHours); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// waitQuietHours waits for the quiet hours of the subnet to end, or returns
+// false once stop is closed. It's only called by the expansion of the subnet
+// so they never hold a worker
+func (p *politeness) waitQuietHours(stop <-chan struct{}) bool {
+	if p.quiet == nil {
+		return true
+	}
+
+	start := time.Now()
+	for wait := p.quiet.remaining(time.Now()); wait > 0; wait = p.quiet.remaining(time.Now()) {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return false
+		}
+	}
+	p.waited(time.Since(start))
+	return true
+}
+
+// connect waits for a connection slot of the /24 of the address, the host
+// holds it while all its probes, fingerprints included, run. It's only called
+// by the expansion of the subnet so a full /24 holds neither the workers nor
+// the tasks of its subnet in memory
+func (p *politeness) connect(ip string) (release func()) {
+	if p.limit.ConnectionsPer24 <= 0 {
+		return func() {}
+	}
+
+	slots := p.limiter.slots(network24(ip), p.limit.ConnectionsPer24)
+	select {
+	case slots <- struct{}{}:
+	default:
+		start := time.Now()
+		slots <- struct{}{}
+		p.waited(time.Since(start))
+	}
+	return func() { <-slots }
+}
+
+// probe waits for the bucket of the subnet to allow one more probe
+func (p *politeness) probe() {
+	if p.limit.ProbesPerSecond <= 0 {
+		return
+	}
+
+	wait := p.limiter.bucket(p.key, p.limit.ProbesPerSecond).reserve(time.Now())
+	if wait > 0 {
+		time.Sleep(wait)
+		p.waited(wait)
+	}
+}
+
+func (p *politeness) waited(wait time.Duration) {
+	if wait > time.Millisecond && viper.GetBool("metrics.enabled") {
+		metrics.UpdateTimer([]string{"scan.rate_limit_wait"}, wait)
+	}
+}
+
+// exclusive tells whether the subnet waits on its own limits while expanding
+func (p *politeness) exclusive() bool {
+	return p.quiet != nil || p.limit.ConnectionsPer24 > 0
+}
+
+func (l *limiter) slots(network string, size int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := fmt.Sprintf("%s %d", network, size)
+	slots, ok := l.connections[key]
+	if !ok {
+		slots = make(chan struct{}, size)
+		l.connections[key] = slots
+	}
+	return slots
+}
+
+func (l *limiter) bucket(key string, rate float64) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the rate is part of the key so a changed config gets a fresh bucket
+	key = fmt.Sprintf("%s %v", key, rate)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(rate)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// network24 returns the /24 of an ipv4 address, ipv6 addresses are grouped
+// by their last byte the same way
+func network24(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	bits := 128
+	if v4 := parsed.To4(); v4 != nil {
+		parsed = v4
+		bits = 32
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(bits-8, bits)), Mask: net.CIDRMask(bits-8, bits)}).String()
+}
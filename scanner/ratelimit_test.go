@@ -0,0 +1,217 @@
+package scanner
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestQuietHours(t *testing.T) {
+	day := func(hour, min int) time.Time {
+		return time.Date(2021, 3, 4, hour, min, 0, 0, time.Local)
+	}
+
+	tt := []struct {
+		window    string
+		now       time.Time
+		remaining time.Duration
+	}{
+		{"08:00-18:00", day(7, 59), 0},
+		{"08:00-18:00", day(8, 0), 10 * time.Hour},
+		{"08:00-18:00", day(17, 30), 30 * time.Minute},
+		{"08:00-18:00", day(18, 0), 0},
+		{"22:00-06:00", day(23, 0), 7 * time.Hour},
+		{"22:00-06:00", day(5, 45), 15 * time.Minute},
+		{"22:00-06:00", day(12, 0), 0},
+	}
+
+	for _, tc := range tt {
+		q, err := parseQuietHours(tc.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if remaining := q.remaining(tc.now); remaining != tc.remaining {
+			t.Errorf("%s at %s: expected %s, found %s", tc.window, tc.now.Format("15:04"), tc.remaining, remaining)
+		}
+	}
+
+	for _, window := range []string{"", "8-18", "25:00-06:00", "22:00-06:61"} {
+		if _, err := parseQuietHours(window); err == nil {
+			t.Errorf("expected %q to be refused", window)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	b := newBucket(10)
+	b.last = now
+
+	if wait := b.reserve(now); wait != 0 {
+		t.Errorf("expected the first probe to go through, waited %s", wait)
+	}
+	if wait := b.reserve(now); wait != 100*time.Millisecond {
+		t.Errorf("expected the second probe to wait 100ms, waited %s", wait)
+	}
+	if wait := b.reserve(now); wait != 200*time.Millisecond {
+		t.Errorf("expected the third probe to wait 200ms, waited %s", wait)
+	}
+	if wait := b.reserve(now.Add(time.Second)); wait != 0 {
+		t.Errorf("expected the bucket to refill after a second, waited %s", wait)
+	}
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	viper.Set("scanner.rate_limit", map[string]interface{}{"probes_per_second": 100})
+	viper.Set("scanner.sites", map[string]interface{}{
+		"adc1": map[string]interface{}{
+			"rate_limit": map[string]interface{}{"probes_per_second": 10, "connections_per_24": 2, "quiet_hours": "08:00-18:00"},
+		},
+	})
+	viper.Set("scanner.subnet_rate_limits", []map[string]interface{}{
+		{"cidr": "10.0.1.0/24", "probes_per_second": 1},
+	})
+	defer func() {
+		viper.Set("scanner.rate_limit", nil)
+		viper.Set("scanner.sites", nil)
+		viper.Set("scanner.subnet_rate_limits", nil)
+	}()
+
+	tt := []struct {
+		subnet *ToScan
+		key    string
+		limit  RateLimit
+	}{
+		{&ToScan{CIDR: "10.0.0.0/24", Site: "adc1"}, "site adc1", RateLimit{ProbesPerSecond: 10, ConnectionsPer24: 2, QuietHours: "08:00-18:00"}},
+		{&ToScan{CIDR: "10.0.1.0/24", Site: "adc1"}, "subnet 10.0.1.0/24", RateLimit{ProbesPerSecond: 1}},
+		{&ToScan{CIDR: "10.1.0.0/24", Site: "ams1"}, "site ams1", RateLimit{ProbesPerSecond: 100}},
+	}
+
+	for _, tc := range tt {
+		key, limit, err := loadRateLimit(tc.subnet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != tc.key || limit != tc.limit {
+			t.Errorf("%s: expected %s %+v, found %s %+v", tc.subnet.CIDR, tc.key, tc.limit, key, limit)
+		}
+	}
+}
+
+func TestConnectionsPer24(t *testing.T) {
+	l := newLimiter()
+	p := &politeness{limiter: l, limit: RateLimit{ConnectionsPer24: 1}}
+
+	release := p.connect("10.0.0.1")
+
+	// another /24 has its own slots
+	p.connect("10.0.1.1")()
+
+	acquired := make(chan struct{})
+	go func() {
+		p.connect("10.0.0.2")()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected the second host of the /24 to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second host to go through once the first one is done")
+	}
+}
+
+func TestPipelineConnectionsPer24(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"test": []map[string]interface{}{
+			{"protocol": "tcp", "port": 1, "timeout": "1s"},
+		},
+	})
+	viper.Set("scanner.rate_limit", map[string]interface{}{"connections_per_24": 1})
+	defer func() {
+		viper.Set("scanner.profiles", nil)
+		viper.Set("scanner.rate_limit", nil)
+	}()
+
+	var mu sync.Mutex
+	var probed int
+	p := newPipeline(db, 4)
+	p.finished = func(result *model.ScanRunSubnet) {
+		mu.Lock()
+		defer mu.Unlock()
+		probed += result.HostsProbed
+	}
+
+	// the first subnet waits on its /24 without holding the second one
+	p.Submit(&ToScan{CIDR: "127.0.0.0/29", Site: "adc1", Profile: "test"})
+	p.Submit(&ToScan{CIDR: "127.0.1.0/30", Site: "adc1", Profile: "test"})
+	p.Wait()
+
+	if probed != 8 {
+		t.Errorf("expected every host to be probed, found %d hosts probed", probed)
+	}
+}
+
+func TestPipelineStop(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"test": []map[string]interface{}{
+			{"protocol": "tcp", "port": 1, "timeout": "1s"},
+		},
+	})
+	viper.Set("scanner.rate_limit", map[string]interface{}{
+		"quiet_hours": fmt.Sprintf("%s-%s", now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04")),
+	})
+	defer func() {
+		viper.Set("scanner.profiles", nil)
+		viper.Set("scanner.rate_limit", nil)
+	}()
+
+	var result *model.ScanRunSubnet
+	p := newPipeline(db, 2)
+	p.finished = func(r *model.ScanRunSubnet) { result = r }
+
+	// the subnet waits for its quiet hours to end until the pipeline is stopped
+	if !p.Submit(&ToScan{CIDR: "127.0.0.0/30", Site: "adc1", Profile: "test"}) {
+		t.Fatal("expected the subnet to be submitted")
+	}
+	p.Stop()
+	if p.Submit(&ToScan{CIDR: "127.0.1.0/30", Site: "adc1", Profile: "test"}) {
+		t.Error("expected a stopped pipeline to refuse subnets")
+	}
+	p.Wait()
+
+	if result == nil || result.State != model.ScanRunFailed || result.Error != errScanInterrupted.Error() || result.HostsProbed != 0 {
+		t.Errorf("expected the subnet to be interrupted before any probe, found %+v", result)
+	}
+}
+
+func TestNetwork24(t *testing.T) {
+	tt := map[string]string{
+		"10.0.0.17":      "10.0.0.0/24",
+		"2001:db8::1:17": "2001:db8::1:0/120",
+	}
+
+	for ip, expected := range tt {
+		if network := network24(ip); network != expected {
+			t.Errorf("%s: expected %s, found %s", ip, expected, network)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// Who triggered a ScanRun
+const (
+	TriggeredByCLI    = "cli"
+	TriggeredByWorker = "worker"
+	TriggeredByAPI    = "api"
+)
+
+// NewScanRun records a new scan run with a pending ScanRunSubnet for each of
+// the subnets and stamps them with the run id. A run without subnets is
+// finished right away
+func NewScanRun(db *gorm.DB, triggeredBy string, subnets []*ToScan) (run *model.ScanRun, err error) {
+	now := time.Now()
+	run = &model.ScanRun{
+		ID:          model.NewScanRunID(),
+		TriggeredBy: triggeredBy,
+		ScannedBy:   viper.GetString("scanner.scanned_by"),
+		State:       model.ScanRunRunning,
+		Subnets:     len(subnets),
+		StartedAt:   now,
+	}
+	if len(subnets) == 0 {
+		run.State = model.ScanRunFinished
+		run.FinishedAt = &now
+	}
+
+	tx := db.Begin()
+	if err = tx.Create(run).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, subnet := range subnets {
+		runSubnet := &model.ScanRunSubnet{
+			ScanRunID: run.ID,
+			CIDR:      subnet.CIDR,
+			Site:      subnet.Site,
+			Profile:   profileName(subnet),
+			AdHoc:     subnet.AdHoc,
+			State:     model.ScanRunPending,
+		}
+		if err = tx.Create(runSubnet).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	for _, subnet := range subnets {
+		subnet.RunID = run.ID
+	}
+
+	return run, nil
+}
+
+// FailScanRun marks the run as failed, used when the subnets to scan can't
+// even be loaded
+func FailScanRun(db *gorm.DB, run *model.ScanRun, cause error) {
+	now := time.Now()
+	err := db.Model(run).Updates(map[string]interface{}{
+		"state":       model.ScanRunFailed,
+		"error":       cause.Error(),
+		"finished_at": &now,
+	}).Error
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": run.ID}).Error(err)
+	}
+}
+
+// startScanRunSubnet marks the subnet of the run as being scanned by us
+func startScanRunSubnet(db *gorm.DB, subnet *ToScan) {
+	if subnet.RunID == "" {
+		return
+	}
+
+	runSubnet := &model.ScanRunSubnet{ScanRunID: subnet.RunID, CIDR: subnet.CIDR}
+	err := db.Model(&model.ScanRunSubnet{}).Where("id = ?", runSubnet.GenID()).Updates(map[string]interface{}{
+		"state":      model.ScanRunRunning,
+		"scanned_by": viper.GetString("scanner.scanned_by"),
+		"started_at": time.Now(),
+	}).Error
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": subnet.RunID, "subnet": subnet.CIDR}).Error(err)
+	}
+}
+
+// finishScanRunSubnet stores the result of the subnet scan, updates the
+// totals of its run and finishes the run once no subnet is pending anymore
+func finishScanRunSubnet(db *gorm.DB, result *model.ScanRunSubnet) {
+	if result.ScanRunID == "" {
+		return
+	}
+
+	now := time.Now()
+	result.FinishedAt = &now
+	result.ID = result.GenID()
+	if result.State == "" {
+		result.State = model.ScanRunFinished
+	}
+
+	if err := db.Save(result).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": result.ScanRunID, "subnet": result.CIDR}).Error(err)
+		return
+	}
+
+	// a single statement so concurrent workers can't overwrite each other's totals
+	err := db.Exec(`UPDATE scan_run SET
+		hosts_probed = (SELECT COALESCE(SUM(hosts_probed), 0) FROM scan_run_subnet WHERE scan_run_id = ?),
+		open_ports = (SELECT COALESCE(SUM(open_ports), 0) FROM scan_run_subnet WHERE scan_run_id = ?),
+		errors = (SELECT COALESCE(SUM(errors), 0) FROM scan_run_subnet WHERE scan_run_id = ?),
+		updated_at = ?
+		WHERE id = ?`, result.ScanRunID, result.ScanRunID, result.ScanRunID, now, result.ScanRunID).Error
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": result.ScanRunID}).Error(err)
+		return
+	}
+
+	var pending int
+	if err = db.Model(&model.ScanRunSubnet{}).Where("scan_run_id = ? and state in (?)", result.ScanRunID, []string{model.ScanRunPending, model.ScanRunRunning}).Count(&pending).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": result.ScanRunID}).Error(err)
+		return
+	}
+
+	if pending == 0 {
+		err = db.Model(&model.ScanRun{}).Where("id = ? and state = ?", result.ScanRunID, model.ScanRunRunning).Updates(map[string]interface{}{
+			"state":       model.ScanRunFinished,
+			"finished_at": &now,
+		}).Error
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "updating scan run", "scan_run_id": result.ScanRunID}).Error(err)
+			return
+		}
+		log.WithFields(log.Fields{"operation": "scan run", "scan_run_id": result.ScanRunID}).Info("scan run finished")
+	}
+}
@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func testDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would get its own in memory database
+	db.DB().SetMaxOpenConns(1)
+	db.SingularTable(true)
+	db.AutoMigrate(
+		&model.ScanRun{},
+		&model.ScanRunSubnet{},
+		&model.ScannedPort{},
+		&model.PortEvent{},
+		&model.IpmiFingerprint{},
+		&model.HTTPFingerprint{},
+		&model.Certificate{},
+		&model.Host{},
+	)
+	return db
+}
+
+func TestScanRun(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	subnets := []*ToScan{
+		{CIDR: "192.168.64.0/24", Site: "adc1"},
+		{CIDR: "192.168.17.0/24", Site: "edc4"},
+	}
+
+	run, err := NewScanRun(db, TriggeredByCLI, subnets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subnet := range subnets {
+		if subnet.RunID != run.ID {
+			t.Errorf("expected subnet %s to belong to run %s, found %q", subnet.CIDR, run.ID, subnet.RunID)
+		}
+	}
+
+	startScanRunSubnet(db, subnets[0])
+	finishScanRunSubnet(db, &model.ScanRunSubnet{ScanRunID: run.ID, CIDR: subnets[0].CIDR, Profile: DefaultProfile, HostsProbed: 254, OpenPorts: 10, State: model.ScanRunFinished})
+
+	stored := model.ScanRun{}
+	if err := db.Where("id = ?", run.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != model.ScanRunRunning || stored.HostsProbed != 254 || stored.FinishedAt != nil {
+		t.Errorf("expected a running run with 254 hosts probed, found %+v", stored)
+	}
+
+	finishScanRunSubnet(db, failedSubnet(&model.ScanRunSubnet{ScanRunID: run.ID, CIDR: subnets[1].CIDR}, errors.New("unknown scan profile: gpu")))
+
+	stored = model.ScanRun{}
+	if err := db.Where("id = ?", run.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != model.ScanRunFinished || stored.HostsProbed != 254 || stored.OpenPorts != 10 || stored.Errors != 1 || stored.FinishedAt == nil {
+		t.Errorf("expected a finished run with 254 hosts probed, 10 open ports and 1 error, found %+v", stored)
+	}
+
+	empty, err := NewScanRun(db, TriggeredByAPI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.State != model.ScanRunFinished {
+		t.Errorf("expected a run without subnets to be finished, found %s", empty.State)
+	}
+}
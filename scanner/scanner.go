@@ -3,11 +3,11 @@ package scanner
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"os"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
+	"time"
 
 	metrics "github.com/bmc-toolbox/gin-go-metrics"
 	"github.com/jinzhu/gorm"
@@ -15,186 +15,332 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/bmc-toolbox/dora/internal/notification"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/bmc-toolbox/dora/storage"
 )
 
-// Kea is the main entry for parsing the kea config file
-type Kea struct {
-	Dhcp4 *Dhcp4 `json:"Dhcp4"`
+// ToScan payload message to scan a network. When Hosts is set only those
+// addresses are scanned instead of the whole network, HostsOnly keeps it that
+// way even when there are none. Leases are recorded as hosts when scanning.
+// Exclude lists the exclusions set by the subnet source, see Exclusions
+type ToScan struct {
+	CIDR      string   `json:"cidr" yaml:"cidr"`
+	Site      string   `json:"site" yaml:"site"`
+	Hosts     []string `json:"hosts,omitempty" yaml:"hosts"`
+	HostsOnly bool     `json:"hosts_only,omitempty" yaml:"-"`
+	Leases    []*Lease `json:"leases,omitempty" yaml:"-"`
+	Exclude   []string `json:"exclude,omitempty" yaml:"exclude"`
+	Profile   string   `json:"profile,omitempty" yaml:"profile"`
+	AdHoc     bool     `json:"ad_hoc,omitempty" yaml:"-"`
+	RunID     string   `json:"run_id,omitempty" yaml:"-"`
 }
 
-// Dhcp4 contains the dhcp information for ipv4 networks
-type Dhcp4 struct {
-	Subnet4 []*Subnet4 `json:"subnet4"`
-}
+// maxExpandedHostBits is the biggest network we walk address by address (a /16 for ipv4)
+const maxExpandedHostBits = 16
 
-// Subnet4 contains all the subnets managed by Kea
-type Subnet4 struct {
-	OptionData []*OptionData `json:"option-data"`
-	Subnet     string        `json:"subnet"`
+func nexIP(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] > 0 {
+			break
+		}
+	}
 }
 
-// OptionData contains the options send to the clients during the dhcp request
-type OptionData struct {
-	Data string `json:"data"`
-	Name string `json:"name"`
-}
+// walkSubnet calls fn with every host address of the network, one at a time,
+// so even the biggest networks are never held in memory
+func walkSubnet(cidr string, fn func(ip string)) error {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
 
-// ToScan payload message to scan a network
-type ToScan struct {
-	CIDR string `json:"cidr"`
-	Site string `json:"site"`
-}
+	ones, bits := ipnet.Mask.Size()
+	hostBits := bits - ones
+	if hostBits > maxExpandedHostBits {
+		return fmt.Errorf("%s is too big to be expanded, its hosts need to be seeded", cidr)
+	}
 
-type scanOption struct {
-	Protocol string
-	Port     int
-}
+	hosts := 1 << uint(hostBits)
+	ip := append(net.IP{}, ipnet.IP...)
+	for i := 0; i < hosts; i++ {
+		// /31 and /32 (or /127 and /128) have no network and broadcast addresses
+		if hostBits <= 1 || (i != 0 && i != hosts-1) {
+			fn(ip.String())
+		}
+		nexIP(ip)
+	}
 
-var scanProfiles = []scanOption{
-	{
-		Protocol: "tcp",
-		Port:     22,
-	},
-	{
-		Protocol: "tcp",
-		Port:     443,
-	},
-	{
-		Protocol: "ipmi",
-		Port:     623,
-	},
+	return nil
 }
 
-// LoadSubnetsFromKea from kea.cfg
-func LoadSubnetsFromKea(content []byte) (subnets []*ToScan) {
-	keaData := &Kea{}
-	err := json.Unmarshal(content, &keaData)
+// eachTarget calls fn with every address we have to probe within a subnet,
+// excluded addresses are skipped. IPv6 networks are never expanded, we only
+// scan the hosts seeded by the source
+func eachTarget(subnet *ToScan, target func(ip string)) error {
+	exclusions, err := ExclusionsFor(subnet)
 	if err != nil {
-		panic(err)
-	}
-
-	keaDomainNameSuffix := viper.GetString("scanner.kea_domain_name_suffix")
-	for _, subnet := range keaData.Dhcp4.Subnet4 {
-		for _, option := range subnet.OptionData {
-			if option.Name == "domain-name" && strings.HasSuffix(option.Data, keaDomainNameSuffix) {
-				if strings.HasSuffix(option.Data, keaDomainNameSuffix) {
-					_, ipv4Net, err := net.ParseCIDR(subnet.Subnet)
-					if err != nil {
-						log.WithFields(log.Fields{"operation": "subnet parsing"}).Warn(err)
-						continue
-					}
-
-					site := strings.TrimSuffix(option.Data, keaDomainNameSuffix)
-					site = strings.Trim(site, ".")
-					toScan := &ToScan{
-						CIDR: ipv4Net.String(),
-						Site: site,
-					}
-
-					subnets = append(subnets, toScan)
-				}
+		return err
+	}
+
+	fn := func(ip string) {
+		if exclusions.Contains(ip) {
+			log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR, "host": ip}).Debug("excluded")
+			return
+		}
+		target(ip)
+	}
+
+	if len(subnet.Hosts) != 0 || subnet.HostsOnly {
+		ips := make([]string, 0, len(subnet.Hosts))
+		for _, host := range subnet.Hosts {
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("invalid host %s in %s", host, subnet.CIDR)
 			}
+			ips = append(ips, ip.String())
+		}
+		for _, ip := range ips {
+			fn(ip)
 		}
+		return nil
 	}
 
-	return subnets
+	_, ipnet, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return err
+	}
+
+	if isIPv6(ipnet.IP) {
+		return fmt.Errorf("no hosts seeded for the ipv6 network %s", subnet.CIDR)
+	}
+
+	return walkSubnet(subnet.CIDR, fn)
 }
 
-func nexIP(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
+// probeHost probes the address of the task with every option of its subnet
+// profile, hands the outcome to the writer and adds it to the subnet result
+func probeHost(db *gorm.DB, w *scanWriter, task *probeTask) {
+	subnet := task.scan.subnet
+	ip := task.ip
+	ScannedBy := task.scan.result.ScannedBy
+
+	// hosts that don't pass the liveness check aren't probed, their ports
+	// keep what the previous scans found or are recorded as not probed
+	alive := true
+	if task.scan.liveness {
+		alive = checkLiveness(task)
+	}
+
+	var openPorts, errors int
+	var lastErr error
+	for _, s := range task.scan.profile {
+		graphiteKey := fmt.Sprintf("scan.%v_%v.scanned_successfully", s.Protocol, s.Port)
+		probeStatus := Result(notProbed)
+		var details string
+		var err error
+		if alive {
+			for attempt := 0; attempt <= s.Retries; attempt++ {
+				task.scan.politeness.probe()
+				probeStatus, details, err = Probe(s.Protocol, ip, s.Port, s.Timeout)
+				if err != nil || probeStatus == open {
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "scanning host", "subnet": subnet.CIDR, "host": ip, "port": s.Port}).Error(err)
+			// failed scan for particular service is not a problem, we don't want separate metric on that
+			errors++
+			lastErr = err
+		}
+		if probeStatus == open {
+			openPorts++
 		}
+
+		sp := &model.ScannedPort{
+			IP:        ip,
+			CIDR:      subnet.CIDR,
+			Port:      s.Port,
+			State:     probeStatus.String(),
+			Details:   details,
+			Site:      subnet.Site,
+			Protocol:  s.Protocol,
+			ScannedBy: ScannedBy,
+		}
+		sp.ID = sp.GenID()
+		w.add(task.scan, sp)
+
+		// the fingerprints are probes of their own, within the slot of the host
+		if s.Protocol == "ipmi" && probeStatus == open {
+			task.scan.politeness.probe()
+			fingerprintIPMI(db, sp, s.Timeout)
+		} else if s.HTTPS && probeStatus == open {
+			task.scan.politeness.probe()
+			fingerprintHTTP(db, sp, viper.GetDuration("scanner.http_fingerprint_timeout"))
+			task.scan.politeness.probe()
+			inspectCertificate(db, sp, s.Timeout)
+		}
+
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{graphiteKey}, 1)
+		}
+	}
+
+	task.scan.mu.Lock()
+	defer task.scan.mu.Unlock()
+	task.scan.result.OpenPorts += openPorts
+	task.scan.result.Errors += errors
+	if lastErr != nil {
+		task.scan.result.Error = lastErr.Error()
 	}
 }
 
-func ipsWithinASubnet(cidr string) ([]string, error) {
-	ip, ipnet, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return nil, err
+// checkLiveness tells whether the address of the task answers the liveness
+// check and counts it in the subnet result when it does
+func checkLiveness(task *probeTask) bool {
+	task.scan.politeness.probe()
+	alive, method := IsAlive(task.ip, viper.GetDuration("scanner.liveness_timeout"), viper.GetBool("scanner.liveness_tcp_fallback"))
+
+	log.WithFields(log.Fields{"operation": "liveness check", "subnet": task.scan.subnet.CIDR, "host": task.ip, "method": method, "alive": alive}).Debug("liveness checked")
+	if viper.GetBool("metrics.enabled") {
+		state := "dead"
+		if alive {
+			state = "alive"
+		}
+		metrics.IncrCounter([]string{fmt.Sprintf("scan.liveness.%s", state)}, 1)
 	}
 
-	var ips []string
-	for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); nexIP(ip) {
-		ips = append(ips, ip.String())
+	if alive {
+		task.scan.mu.Lock()
+		task.scan.result.HostsAlive++
+		task.scan.mu.Unlock()
 	}
-	// remove network address and broadcast address
-	return ips[1 : len(ips)-1], nil
+	return alive
 }
 
-func scan(input <-chan *ToScan, db *gorm.DB) {
-	ScannedBy := viper.GetString("scanner.scanned_by")
-	for subnet := range input {
+// recordHosts stores the leases of the subnet as hosts
+func recordHosts(db *gorm.DB, subnet *ToScan) {
+	for _, lease := range subnet.Leases {
+		host := &model.Host{
+			IP:          lease.Address,
+			Site:        subnet.Site,
+			CIDR:        subnet.CIDR,
+			HwAddress:   lease.HwAddress,
+			Hostname:    lease.Hostname,
+			LeaseExpire: lease.Expire,
+		}
+		if err := db.Save(host).Error; err != nil {
+			log.WithFields(log.Fields{"operation": "storing host", "subnet": subnet.CIDR, "host": lease.Address}).Error(err)
+		}
+	}
+}
 
-		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Info("network scan started")
+// recordPortEvent stores the change of state of the port and notifies about it
+func recordPortEvent(db *gorm.DB, sp *model.ScannedPort, oldState string) {
+	event := &model.PortEvent{
+		ScannedPortID: sp.ID,
+		Site:          sp.Site,
+		CIDR:          sp.CIDR,
+		IP:            sp.IP,
+		Port:          sp.Port,
+		Protocol:      sp.Protocol,
+		ScannedBy:     sp.ScannedBy,
+		OldState:      oldState,
+		NewState:      sp.State,
+		ChangedAt:     time.Now(),
+	}
 
-		ips, err := ipsWithinASubnet(subnet.CIDR)
-		if err != nil {
-			log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Error(err)
-			continue
-		}
+	if err := db.Create(event).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "storing port event", "host": sp.IP, "port": sp.Port}).Error(err)
+		return
+	}
 
-		for _, s := range scanProfiles {
-			for _, ip := range ips {
-				graphiteKey := fmt.Sprintf("scan.%v_%v.scanned_successfully", s.Protocol, s.Port)
-				probeStatus, err := Probe(s.Protocol, ip, s.Port)
-				if err != nil {
-					log.WithFields(log.Fields{"operation": "scanning host", "subnet": subnet.CIDR, "host": ip, "port": s.Port}).Error(err)
-					// failed scan for particular service is not a problem, we don't want separate metric on that
-				}
+	log.WithFields(log.Fields{"operation": "port event", "host": sp.IP, "port": sp.Port, "protocol": sp.Protocol, "old_state": oldState, "new_state": sp.State}).Info("port changed state")
+	notification.NotifyChange(fmt.Sprintf("%s/%s/%s", viper.GetString("url"), "port_events", event.ID))
+	if viper.GetBool("metrics.enabled") {
+		metrics.IncrCounter([]string{fmt.Sprintf("scan.port_events.%s_to_%s", oldState, sp.State)}, 1)
+	}
+}
 
-				sp := model.ScannedPort{
-					IP:        ip,
-					CIDR:      subnet.CIDR,
-					Port:      s.Port,
-					State:     probeStatus.String(),
-					Site:      subnet.Site,
-					Protocol:  s.Protocol,
-					ScannedBy: ScannedBy,
-				}
-				sp.ID = sp.GenID()
+// fingerprintIPMI stores what the bmc behind an open ipmi port tells about itself
+func fingerprintIPMI(db *gorm.DB, sp *model.ScannedPort, timeout time.Duration) {
+	fingerprint, err := FingerprintIPMI(sp.IP, sp.Port, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "ipmi fingerprint", "host": sp.IP, "port": sp.Port}).Debug(err)
+		return
+	}
 
-				if err = db.Save(&sp).Error; err != nil {
-					log.WithFields(log.Fields{"operation": "storing scan", "subnet": subnet.CIDR, "host": ip, "port": s.Port}).Error(err)
-					graphiteKey = "scan.db_save_failed"
-				}
-				if viper.GetBool("metrics.enabled") {
-					metrics.IncrCounter([]string{graphiteKey}, 1)
-				}
-			}
-		}
+	fingerprint.ScannedPortID = sp.ID
+	if err = db.Save(fingerprint).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "storing ipmi fingerprint", "host": sp.IP, "port": sp.Port}).Error(err)
+	}
+}
+
+// fingerprintHTTP stores what the web interface behind an open https port
+// tells about itself and seeds the discover hint of the ip with it, hints
+// already learned by the collector are kept as they are known to work
+func fingerprintHTTP(db *gorm.DB, sp *model.ScannedPort, timeout time.Duration) {
+	fingerprint, err := FingerprintHTTP(sp.IP, sp.Port, timeout)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "http fingerprint", "host": sp.IP, "port": sp.Port}).Debug(err)
+		return
+	}
+
+	fingerprint.ScannedPortID = sp.ID
+	if err = db.Save(fingerprint).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "storing http fingerprint", "host": sp.IP, "port": sp.Port}).Error(err)
+		return
+	}
 
-		log.WithFields(log.Fields{"operation": "subnet expansion", "subnet": subnet.CIDR}).Info("network scan finished")
+	if fingerprint.Hint == "" {
+		return
+	}
+
+	hint := model.DiscoverHint{}
+	if err = db.Where(model.DiscoverHint{IP: sp.IP}).Attrs(model.DiscoverHint{Hint: fingerprint.Hint}).FirstOrCreate(&hint).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "storing discover hint", "host": sp.IP}).Error(err)
 	}
 }
 
-// ReadKeaConfig reads the config configuration file and returns its data
-func ReadKeaConfig() (content []byte, err error) {
-	keaConfig := viper.GetString("scanner.kea_config")
-	content, err = ioutil.ReadFile(keaConfig)
+// inspectCertificate stores the leaf certificate presented by an open https port
+func inspectCertificate(db *gorm.DB, sp *model.ScannedPort, timeout time.Duration) {
+	certificate, err := InspectCertificate(sp.IP, sp.Port, timeout)
 	if err != nil {
-		return content, err
+		log.WithFields(log.Fields{"operation": "certificate inspection", "host": sp.IP, "port": sp.Port}).Debug(err)
+		return
 	}
 
-	return content, err
+	certificate.ScannedPortID = sp.ID
+	if err = db.Save(certificate).Error; err != nil {
+		log.WithFields(log.Fields{"operation": "storing certificate", "host": sp.IP, "port": sp.Port}).Error(err)
+	}
 }
 
-func LoadSubnets(source string, subnetsToScan []string, site []string) (subnets []*ToScan) {
-	content, err := ReadKeaConfig()
+// LoadSubnets loads the subnets from the given source and filters them by
+// the requested networks or sites
+func LoadSubnets(source string, subnetsToScan []string, site []string) (subnets []*ToScan, err error) {
+	subnetSource, err := NewSubnetSource(source)
+	if err != nil {
+		return subnets, err
+	}
+
+	subnets, err = subnetSource.Subnets()
 	if err != nil {
-		log.WithFields(log.Fields{"operation": "loading subnets"}).Error(err)
-		os.Exit(1)
+		return subnets, err
 	}
 
-	if source == "kea" {
-		subnets = LoadSubnetsFromKea(content)
+	if hostsFile := viper.GetString("scanner.ipv6_hosts_file"); hostsFile != "" {
+		hosts, err := readHostsFile(hostsFile)
+		if err != nil {
+			return subnets, err
+		}
+		seedIPv6Hosts(subnets, hosts)
 	}
 
 	if subnetsToScan[0] == "all" && site[0] == "all" {
-		return subnets
+		return subnets, err
 	} else if subnetsToScan[0] == "all" && site[0] != "all" {
 		filteredSubnets := make([]*ToScan, 0)
 		for _, subnet := range subnets {
@@ -209,6 +355,10 @@ func LoadSubnets(source string, subnetsToScan []string, site []string) (subnets
 		filteredSubnets := make([]*ToScan, 0)
 		for _, subnet := range subnets {
 			for _, s := range subnetsToScan {
+				// compare the normalized form, 2001:DB8:0::/64 is 2001:db8::/64
+				if _, ipNet, err := net.ParseCIDR(s); err == nil {
+					s = ipNet.String()
+				}
 				if s == subnet.CIDR {
 					filteredSubnets = append(filteredSubnets, subnet)
 				}
@@ -217,69 +367,121 @@ func LoadSubnets(source string, subnetsToScan []string, site []string) (subnets
 		subnets = filteredSubnets
 	}
 
-	return subnets
+	return subnets, err
 }
 
 // ListSubnets all or a list of given subnets
-func ListSubnets(subnetsToQuery []string, site []string) (subnets []*ToScan) {
+func ListSubnets(subnetsToQuery []string, site []string) (subnets []*ToScan, err error) {
 	return LoadSubnets(viper.GetString("scanner.subnet_source"), subnetsToQuery, site)
 }
 
-// ScanNetworks scan specific or all networks and try to find chassis, blades and servers
-func ScanNetworks(subnetsToScan []string, site []string) {
-	concurrency := viper.GetInt("scanner.concurrency")
+// SourceSubnets returns the networks of the subnet source as model Subnets
+func SourceSubnets() (subnets []*model.Subnet, err error) {
+	toScan, err := ListSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		return subnets, err
+	}
+	return sourceSubnets(toScan), nil
+}
+
+func sourceSubnets(toScan []*ToScan) (subnets []*model.Subnet) {
+	for _, subnet := range toScan {
+		subnets = append(subnets, &model.Subnet{CIDR: subnet.CIDR, Site: subnet.Site})
+	}
+	return subnets
+}
 
-	cc := make(chan *ToScan, concurrency)
-	wg := sync.WaitGroup{}
+// ScanNetworks scan specific or all networks and try to find chassis, blades and servers,
+// using the given scan profile or, when empty, the profile configured for each site
+func ScanNetworks(subnetsToScan []string, site []string, adHocSite string, profile string) {
 	db := storage.InitDB()
+	p := newPipeline(db, viper.GetInt("scanner.concurrency"))
+
+	var subnets []*ToScan
+	var loadErr error
+	if subnetsToScan[0] == "all" {
+		subnets, loadErr = LoadSubnets(viper.GetString("scanner.subnet_source"), subnetsToScan, site)
+	} else {
+		subnets, loadErr = ResolveSubnets(subnetsToScan, site, adHocSite)
+	}
+	if loadErr != nil {
+		log.WithFields(log.Fields{"operation": "loading subnets", "source": viper.GetString("scanner.subnet_source")}).Error(loadErr)
+	}
 
-	wg.Add(concurrency)
-	for i := 0; i < concurrency; i++ {
-		go func(input <-chan *ToScan, db *gorm.DB, wg *sync.WaitGroup) {
-			defer wg.Done()
-			scan(input, db)
-		}(cc, db, &wg)
+	for idx := range subnets {
+		subnets[idx].Profile = profile
 	}
 
-	subnets := LoadSubnets(viper.GetString("scanner.subnet_source"), subnetsToScan, site)
+	run, err := NewScanRun(db, TriggeredByCLI, subnets)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "creating scan run"}).Error(err)
+	} else {
+		if loadErr != nil {
+			FailScanRun(db, run, loadErr)
+		}
+		log.WithFields(log.Fields{"operation": "scan run", "scan_run_id": run.ID, "subnets": len(subnets)}).Info("scan run started")
+	}
 
 	for idx := range subnets {
-		cc <- subnets[idx]
+		p.Submit(subnets[idx])
 	}
 
-	close(cc)
-	wg.Wait()
+	p.Wait()
+
+	// the stale ports were marked after each subnet, the ones of networks gone from the source are left
+	if viper.GetBool("scanner.prune_after_scan") && loadErr == nil {
+		cidrs, err := sourceCIDRs()
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "pruning"}).Error(err)
+			return
+		}
+		// the networks scanned ad hoc are kept as dora prune does
+		adHoc, err := adHocCIDRs(db)
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "pruning"}).Error(err)
+			return
+		}
+		removed, err := RemoveMissingSubnets(db, append(cidrs, adHoc...))
+		if err != nil {
+			log.WithFields(log.Fields{"operation": "pruning"}).Error(err)
+			return
+		}
+		log.WithFields(log.Fields{"operation": "pruning", "removed": removed}).Info("scanned ports of missing subnets removed")
+	}
 }
 
-// ScanNetworksWorker scan specific or all networks and try to find chassis, blades and servers
-func ScanNetworksWorker() {
+// ScanNetworksWorker scan specific or all networks and try to find chassis, blades and servers.
+// On SIGINT or SIGTERM it stops taking messages and expanding the subnets,
+// the returned wait blocks until the probes in flight are done and stored
+func ScanNetworksWorker() (wait func()) {
 	nc, err := nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
 	if err != nil {
 		log.Fatalf("Subscriber unable to connect: %v\n", err)
 	}
 
-	concurrency := viper.GetInt("scanner.concurrency")
-	cc := make(chan *ToScan, concurrency)
-	wg := sync.WaitGroup{}
 	db := storage.InitDB()
+	p := newPipeline(db, viper.GetInt("scanner.concurrency"))
 
-	wg.Add(concurrency)
-	for i := 0; i < concurrency; i++ {
-		go func(input <-chan *ToScan, db *gorm.DB, wg *sync.WaitGroup) {
-			defer wg.Done()
-			scan(input, db)
-		}(cc, db, &wg)
-	}
-
-	nc.QueueSubscribe("dora::scan", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
+	sub, err := nc.QueueSubscribe("dora::scan", viper.GetString("collector.worker.queue"), func(msg *nats.Msg) {
 		t := &ToScan{}
 		err := json.Unmarshal(msg.Data, t)
 		if err != nil {
 			log.WithFields(log.Fields{"operation": "subnet scan"}).Error(err)
 			return
 		}
-		cc <- t
+		// messages published without a run get one of their own
+		if t.RunID == "" {
+			if _, err := NewScanRun(db, TriggeredByWorker, []*ToScan{t}); err != nil {
+				log.WithFields(log.Fields{"operation": "creating scan run", "subnet": t.CIDR}).Error(err)
+			}
+		}
+		if !p.Submit(t) {
+			log.WithFields(log.Fields{"operation": "subnet scan", "subnet": t.CIDR}).Warn("draining, dropping the message")
+		}
 	})
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "error subscribing to the queue"}).Fatal(err)
+	}
 	nc.Flush()
 
 	if err := nc.LastError(); err != nil {
@@ -287,6 +489,24 @@ func ScanNetworksWorker() {
 	}
 
 	log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": "dora::scan"}).Info("Subscribed to queue")
-	//	close(cc)
-	//	wg.Wait()
+
+	drained := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		log.WithFields(log.Fields{"operation": "shutdown"}).Warnf("received %s, draining the scans", sig)
+
+		if err := sub.Unsubscribe(); err != nil {
+			log.WithFields(log.Fields{"operation": "unsubscribing from the queue"}).Error(err)
+		}
+		p.Stop()
+		p.Wait()
+		nc.Close()
+		log.WithFields(log.Fields{"operation": "shutdown"}).Info("scans drained")
+		close(drained)
+	}()
+
+	return func() { <-drained }
 }
@@ -1,9 +1,17 @@
 package scanner
 
 import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -36,7 +44,10 @@ func TestLoadConfig(t *testing.T) {
 
 	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
 	for _, tc := range tt {
-		networks := LoadSubnetsFromKea(tc.content)
+		networks, err := LoadSubnetsFromKea(tc.content)
+		if err != nil {
+			t.Fatal(err)
+		}
 		found := false
 		foundNetworks := make([]string, 0)
 		for _, network := range networks {
@@ -53,3 +64,241 @@ func TestLoadConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadConfigKeaFeatures(t *testing.T) {
+	content := []byte(`
+// kea allows comments
+{
+  # in all the flavours
+  "Dhcp4": {
+    /* global options are inherited */
+    "option-data": [{"name": "domain-name", "data": "adc1.bmc.example.com"}],
+    "subnet4": [
+      {"subnet": "192.168.64.0/24"},
+      {"subnet": "192.168.65.0/24", "user-context": {"site": "adc2"}},
+      {"subnet": "192.168.66.0/24", "user-context": {"dora-exclude": true}}
+    ],
+    "shared-networks": [
+      {
+        "name": "edc4",
+        "option-data": [{"name": "domain-name", "data": "edc4.bmc.example.com"}],
+        "user-context": {"comment": "http://not.a.comment/"},
+        "subnet4": [
+          {"subnet": "192.168.17.0/24"},
+          {"subnet": "192.168.18.0/24", "option-data": [{"name": "domain-name", "data": "edc4.ext.example.com"}]}
+        ]
+      }
+    ]
+  }
+}`)
+
+	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
+	viper.SetDefault("scanner.kea_site_key", "site")
+	viper.SetDefault("scanner.kea_exclude_key", "dora-exclude")
+
+	subnets, err := LoadSubnetsFromKea(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ToScan{
+		{CIDR: "192.168.64.0/24", Site: "adc1"},
+		{CIDR: "192.168.65.0/24", Site: "adc2"},
+		{CIDR: "192.168.17.0/24", Site: "edc4"},
+	}
+	if !reflect.DeepEqual(subnets, expected) {
+		t.Errorf("expected %+v, found %+v", expected, subnets)
+	}
+
+	if _, err := LoadSubnetsFromKea([]byte(`{"Dhcp4": {`)); err == nil {
+		t.Errorf("expected an error for an invalid kea config")
+	}
+}
+
+func TestReadKeaConfigWithIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-kea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"kea-dhcp4.conf": `{"Dhcp4": {"subnet4": [<?include "subnets.json"?>]}}`,
+		"subnets.json":   `{"subnet": "192.168.64.0/24", "user-context": {"site": "adc1"}} // adc1`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viper.SetDefault("scanner.kea_site_key", "site")
+	subnets, err := (&KeaSource{Path: filepath.Join(dir, "kea-dhcp4.conf")}).Subnets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ToScan{{CIDR: "192.168.64.0/24", Site: "adc1"}}
+	if !reflect.DeepEqual(subnets, expected) {
+		t.Errorf("expected %+v, found %+v", expected, subnets)
+	}
+}
+
+func TestLoadConfigKeaIPv6(t *testing.T) {
+	content := []byte(`{"Dhcp6": {"subnet6": [
+		{"subnet": "2001:DB8:0:1::/64", "user-context": {"site": "adc1"},
+		 "reservations": [{"duid": "01:02:03", "ip-addresses": ["2001:db8:0:1::10", "2001:db8:ffff::10"]}]}
+	]}}`)
+
+	viper.SetDefault("scanner.kea_site_key", "site")
+	subnets, err := LoadSubnetsFromKea(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ToScan{{CIDR: "2001:db8:0:1::/64", Site: "adc1", Hosts: []string{"2001:db8:0:1::10"}}}
+	if !reflect.DeepEqual(subnets, expected) {
+		t.Errorf("expected %+v, found %+v", expected, subnets)
+	}
+
+	seedIPv6Hosts(subnets, []string{"2001:db8:0:1::10", "2001:db8:0:1:0:0:0:20", "192.168.0.1"})
+	if hosts := subnets[0].Hosts; !reflect.DeepEqual(hosts, []string{"2001:db8:0:1::10", "2001:db8:0:1::20"}) {
+		t.Errorf("unexpected seeded hosts %v", hosts)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	tt := []struct {
+		subnet  *ToScan
+		targets []string
+		err     bool
+	}{
+		{&ToScan{CIDR: "192.168.0.0/30"}, []string{"192.168.0.1", "192.168.0.2"}, false},
+		{&ToScan{CIDR: "192.168.0.0/31"}, []string{"192.168.0.0", "192.168.0.1"}, false},
+		{&ToScan{CIDR: "192.168.0.0/24", Hosts: []string{"192.168.0.10"}}, []string{"192.168.0.10"}, false},
+		{&ToScan{CIDR: "2001:db8::/64", Hosts: []string{"2001:DB8::10"}}, []string{"2001:db8::10"}, false},
+		{&ToScan{CIDR: "2001:db8::/64"}, nil, true},
+	}
+
+	for _, tc := range tt {
+		var ips []string
+		err := eachTarget(tc.subnet, func(ip string) { ips = append(ips, ip) })
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.subnet.CIDR, err)
+		}
+		if !reflect.DeepEqual(ips, tc.targets) {
+			t.Errorf("%s: expected %v, found %v", tc.subnet.CIDR, tc.targets, ips)
+		}
+	}
+}
+
+func TestWalkSubnet(t *testing.T) {
+	var count int
+	var first, last string
+	err := walkSubnet("10.0.0.0/16", func(ip string) {
+		if count == 0 {
+			first = ip
+		}
+		last = ip
+		count++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 65534 || first != "10.0.0.1" || last != "10.0.255.254" {
+		t.Errorf("expected 65534 hosts from 10.0.0.1 to 10.0.255.254, found %d from %s to %s", count, first, last)
+	}
+
+	if err := walkSubnet("10.0.0.0/8", func(string) {}); err == nil {
+		t.Error("expected a /8 to be refused")
+	}
+}
+
+func TestPipelineSharesWorkers(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"test": []map[string]interface{}{
+			{"protocol": "tcp", "port": 1, "timeout": "1s"},
+		},
+	})
+	defer viper.Set("scanner.profiles", nil)
+
+	var mu sync.Mutex
+	results := make(map[string]*model.ScanRunSubnet)
+	p := newPipeline(db, 8)
+	p.finished = func(result *model.ScanRunSubnet) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[result.CIDR] = result
+	}
+
+	p.Submit(&ToScan{CIDR: "127.0.0.0/29", Site: "adc1", Profile: "test"})
+	p.Submit(&ToScan{CIDR: "127.0.1.0/30", Site: "adc1", Profile: "test"})
+	p.Submit(&ToScan{CIDR: "127.0.2.0/30", Site: "adc1", Profile: "unknown"})
+	p.Wait()
+
+	if r := results["127.0.0.0/29"]; r == nil || r.State != model.ScanRunFinished || r.HostsProbed != 6 {
+		t.Errorf("expected 6 hosts probed in 127.0.0.0/29, found %+v", r)
+	}
+	if r := results["127.0.1.0/30"]; r == nil || r.State != model.ScanRunFinished || r.HostsProbed != 2 {
+		t.Errorf("expected 2 hosts probed in 127.0.1.0/30, found %+v", r)
+	}
+	if r := results["127.0.2.0/30"]; r == nil || r.State != model.ScanRunFailed {
+		t.Errorf("expected the scan of 127.0.2.0/30 to fail, found %+v", r)
+	}
+
+	var ports int
+	if err := db.Model(&model.ScannedPort{}).Count(&ports).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ports != 8 {
+		t.Errorf("expected 8 scanned ports, found %d", ports)
+	}
+}
+
+func TestScanSubnetPortEvents(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+
+	viper.Set("scanner.profiles", map[string]interface{}{
+		"test": []map[string]interface{}{
+			{"protocol": "tcp", "port": port, "timeout": "1s"},
+		},
+	})
+	defer viper.Set("scanner.profiles", nil)
+
+	subnet := &ToScan{CIDR: "127.0.0.1/32", Site: "adc1", Profile: "test"}
+
+	scanSubnet := func() (results []*model.ScanRunSubnet) {
+		p := newPipeline(db, 2)
+		p.finished = func(result *model.ScanRunSubnet) { results = append(results, result) }
+		p.Submit(subnet)
+		p.Wait()
+		return results
+	}
+
+	results := scanSubnet()
+	if len(results) != 1 || results[0].State != model.ScanRunFinished || results[0].HostsProbed != 1 || results[0].OpenPorts != 1 {
+		t.Errorf("expected one open port, found %+v", results)
+	}
+
+	l.Close()
+	scanSubnet()
+	scanSubnet()
+
+	var events []model.PortEvent
+	if err := db.Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].OldState != "open" || events[0].NewState != "closed" || events[0].Port != port {
+		t.Errorf("expected a single open to closed event, found %+v", events)
+	}
+}
@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrUnknownSubnetSource is returned when scanner.subnet_source points to a source we don't support
+var ErrUnknownSubnetSource = errors.New("unknown subnet source")
+
+// SubnetSource is implemented by everything that is able to tell dora which
+// networks exist and to which site they belong
+type SubnetSource interface {
+	// Name returns the name of the source as used in scanner.subnet_source
+	Name() string
+	// Subnets returns all networks known by the source
+	Subnets() ([]*ToScan, error)
+}
+
+// NewSubnetSource returns the SubnetSource matching the given name, configured
+// from the scanner section of the config file
+func NewSubnetSource(source string) (SubnetSource, error) {
+	switch source {
+	case "kea":
+		return &KeaSource{
+			Path:        viper.GetString("scanner.kea_config"),
+			Path6:       viper.GetString("scanner.kea_config6"),
+			Leases6Path: viper.GetString("scanner.kea_leases6"),
+			LeasesOnly:  viper.GetBool("scanner.kea_leases_only"),
+			Leases4Path: viper.GetString("scanner.kea_leases4"),
+
+			LeaseDatabaseType:    viper.GetString("scanner.kea_lease_database_type"),
+			LeaseDatabaseOptions: viper.GetString("scanner.kea_lease_database_options"),
+		}, nil
+	case "file":
+		return &FileSource{Path: viper.GetString("scanner.subnet_file")}, nil
+	case "dhcpd":
+		return &DhcpdSource{Path: viper.GetString("scanner.dhcpd_config")}, nil
+	case "directory":
+		return &DirectorySource{Path: viper.GetString("scanner.subnet_directory")}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSubnetSource, source)
+	}
+}
+
+// siteFromDomainName extracts the site from a domain name ending with the
+// configured scanner.kea_domain_name_suffix, e.g. ams4.bmc.example.com -> ams4
+func siteFromDomainName(domainName string) (site string, ok bool) {
+	suffix := viper.GetString("scanner.kea_domain_name_suffix")
+	if !strings.HasSuffix(domainName, suffix) {
+		return site, false
+	}
+
+	site = strings.TrimSuffix(domainName, suffix)
+	site = strings.Trim(site, ".")
+	return site, true
+}
+
+func isIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.To16() != nil
+}
+
+// seedIPv6Hosts adds each host to the ipv6 subnet containing it, since ipv6
+// subnets are too big to be enumerated these are the only hosts we scan there
+func seedIPv6Hosts(subnets []*ToScan, hosts []string) {
+	for _, subnet := range subnets {
+		_, ipNet, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil || !isIPv6(ipNet.IP) {
+			continue
+		}
+
+		known := make(map[string]bool)
+		for _, host := range subnet.Hosts {
+			known[host] = true
+		}
+
+		for _, host := range hosts {
+			ip := net.ParseIP(host)
+			if ip == nil || !ipNet.Contains(ip) || known[ip.String()] {
+				continue
+			}
+			known[ip.String()] = true
+			subnet.Hosts = append(subnet.Hosts, ip.String())
+		}
+	}
+}
+
+// readHostsFile reads a list of addresses, one per line, ignoring empty lines
+// and lines starting with #
+func readHostsFile(path string) (hosts []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return hosts, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if net.ParseIP(line) == nil {
+			return hosts, fmt.Errorf("invalid address in %s: %s", path, line)
+		}
+		hosts = append(hosts, line)
+	}
+
+	return hosts, s.Err()
+}
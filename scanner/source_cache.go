@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// SourceCache keeps the subnets of the subnet source and their exclusions
+// for a while, so the api doesn't read the source, the kea lease database
+// included, on every request
+type SourceCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	loadedAt   time.Time
+	subnets    []*model.Subnet
+	exclusions *ExclusionChecker
+}
+
+// NewSourceCache returns a cache reloading the subnet source once it's older
+// than ttl, a ttl of 0 reloads it every time
+func NewSourceCache(ttl time.Duration) *SourceCache {
+	return &SourceCache{ttl: ttl}
+}
+
+// Subnets returns the networks of the subnet source as model Subnets
+func (c *SourceCache) Subnets() (subnets []*model.Subnet, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = c.load(); err != nil {
+		return subnets, err
+	}
+	return c.subnets, nil
+}
+
+// ExclusionChecker returns the exclusions of the subnets of the subnet source
+func (c *SourceCache) ExclusionChecker() (*ExclusionChecker, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c.exclusions, nil
+}
+
+// Reset forgets what was loaded, the next call reads the subnet source again
+func (c *SourceCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+// load reads the subnet source when what was loaded expired, a failed load
+// isn't kept
+func (c *SourceCache) load() error {
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl {
+		return nil
+	}
+
+	toScan, err := ListSubnets([]string{"all"}, []string{"all"})
+	if err != nil {
+		return err
+	}
+	exclusions, err := newExclusionChecker(toScan)
+	if err != nil {
+		return err
+	}
+
+	c.subnets, c.exclusions, c.loadedAt = sourceSubnets(toScan), exclusions, time.Now()
+	return nil
+}
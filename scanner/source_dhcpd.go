@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DhcpdSource loads the subnets from an ISC dhcpd.conf file. The site is
+// derived from the domain-name option of each subnet, the same way we do for kea
+type DhcpdSource struct {
+	Path string
+}
+
+// Name returns the name of the source
+func (d *DhcpdSource) Name() string {
+	return "dhcpd"
+}
+
+// Subnets reads the dhcpd config file and returns the subnets found in it
+func (d *DhcpdSource) Subnets() (subnets []*ToScan, err error) {
+	content, err := ioutil.ReadFile(d.Path)
+	if err != nil {
+		return subnets, err
+	}
+
+	return LoadSubnetsFromDhcpd(content)
+}
+
+// LoadSubnetsFromDhcpd parses the subnet declarations of a dhcpd.conf. Options
+// declared in an enclosing shared-network or group are inherited by the subnets
+func LoadSubnetsFromDhcpd(content []byte) (subnets []*ToScan, err error) {
+	tokens, err := dhcpdTokenize(string(content))
+	if err != nil {
+		return subnets, err
+	}
+
+	// each scope carries the domain-name visible inside it and, when the scope
+	// is a subnet declaration, the subnet itself
+	type scope struct {
+		domainName string
+		subnet     *net.IPNet
+	}
+
+	scopes := []*scope{{}}
+	var statement []string
+	for _, token := range tokens {
+		current := scopes[len(scopes)-1]
+		switch token {
+		case "{":
+			s := &scope{domainName: current.domainName}
+			if len(statement) == 4 && statement[0] == "subnet" && statement[2] == "netmask" {
+				s.subnet, err = dhcpdSubnet(statement[1], statement[3])
+				if err != nil {
+					return subnets, err
+				}
+			}
+			scopes = append(scopes, s)
+			statement = nil
+		case "}":
+			if len(scopes) == 1 {
+				return subnets, fmt.Errorf("unexpected } in dhcpd config")
+			}
+			scopes = scopes[:len(scopes)-1]
+			statement = nil
+
+			if current.subnet == nil {
+				continue
+			}
+
+			site, ok := siteFromDomainName(current.domainName)
+			if !ok {
+				log.WithFields(log.Fields{"operation": "subnet parsing", "subnet": current.subnet.String()}).Debug("subnet without a matching domain-name")
+				continue
+			}
+
+			subnets = append(subnets, &ToScan{
+				CIDR: current.subnet.String(),
+				Site: site,
+			})
+		case ";":
+			if len(statement) == 3 && statement[0] == "option" && statement[1] == "domain-name" {
+				current.domainName = statement[2]
+			}
+			statement = nil
+		default:
+			statement = append(statement, token)
+		}
+	}
+
+	if len(scopes) != 1 {
+		return subnets, fmt.Errorf("unbalanced braces in dhcpd config")
+	}
+
+	return subnets, nil
+}
+
+func dhcpdSubnet(address string, netmask string) (*net.IPNet, error) {
+	ip := net.ParseIP(address).To4()
+	mask := net.ParseIP(netmask).To4()
+	if ip == nil || mask == nil {
+		return nil, fmt.Errorf("invalid subnet declaration: subnet %s netmask %s", address, netmask)
+	}
+
+	ipNet := &net.IPNet{IP: ip, Mask: net.IPMask(mask)}
+	ones, bits := ipNet.Mask.Size()
+	if bits == 0 {
+		return nil, fmt.Errorf("invalid netmask: %s", netmask)
+	}
+	ipNet.IP = ip.Mask(ipNet.Mask)
+	ipNet.Mask = net.CIDRMask(ones, bits)
+
+	return ipNet, nil
+}
+
+// dhcpdTokenize splits the dhcpd config into words, quoted strings and the
+// { } ; delimiters, dropping the # comments
+func dhcpdTokenize(content string) (tokens []string, err error) {
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '#':
+			flush()
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			flush()
+			end := strings.IndexByte(content[i+1:], '"')
+			if end == -1 {
+				return tokens, fmt.Errorf("unterminated string in dhcpd config")
+			}
+			tokens = append(tokens, content[i+1:i+1+end])
+			i += end + 1
+		case c == '{' || c == '}' || c == ';':
+			flush()
+			tokens = append(tokens, string(c))
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
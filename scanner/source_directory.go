@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// DirectorySource loads the subnets from a directory with one file per site.
+// The file name without extension is the site and each line of the file is a
+// network, empty lines and lines starting with # are ignored
+type DirectorySource struct {
+	Path string
+}
+
+// Name returns the name of the source
+func (d *DirectorySource) Name() string {
+	return "directory"
+}
+
+// Subnets reads all files in the directory and returns the subnets found in them
+func (d *DirectorySource) Subnets() (subnets []*ToScan, err error) {
+	files, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return subnets, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(d.Path, file.Name()))
+		if err != nil {
+			return subnets, err
+		}
+
+		site := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		siteSubnets, err := loadSubnetsFromSiteFile(content, site)
+		if err != nil {
+			return subnets, err
+		}
+		subnets = append(subnets, siteSubnets...)
+	}
+
+	return subnets, nil
+}
+
+func loadSubnetsFromSiteFile(content []byte, site string) (subnets []*ToScan, err error) {
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		toScan, err := newToScan(line, site)
+		if err != nil {
+			return subnets, err
+		}
+		subnets = append(subnets, toScan)
+	}
+
+	return subnets, s.Err()
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FileSource loads the subnets from a static yaml or csv file mapping each
+// network to its site
+//
+// yaml:
+//
+//	subnets:
+//	  - cidr: 192.168.0.0/24
+//	    site: ams4
+//	    exclude: [+1, 192.168.0.250-192.168.0.254]
+//
+// csv:
+//
+//	192.168.0.0/24,ams4
+type FileSource struct {
+	Path string
+}
+
+type subnetFile struct {
+	Subnets []*ToScan `yaml:"subnets"`
+}
+
+// Name returns the name of the source
+func (f *FileSource) Name() string {
+	return "file"
+}
+
+// Subnets reads the file and returns the subnets found in it
+func (f *FileSource) Subnets() (subnets []*ToScan, err error) {
+	content, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return subnets, err
+	}
+
+	switch strings.ToLower(filepath.Ext(f.Path)) {
+	case ".yaml", ".yml":
+		return LoadSubnetsFromYAML(content)
+	case ".csv":
+		return LoadSubnetsFromCSV(content)
+	default:
+		return subnets, fmt.Errorf("unsupported subnet file format: %s", f.Path)
+	}
+}
+
+// LoadSubnetsFromYAML parses a list of subnets in the yaml format
+func LoadSubnetsFromYAML(content []byte) (subnets []*ToScan, err error) {
+	data := &subnetFile{}
+	if err = yaml.Unmarshal(content, data); err != nil {
+		return subnets, err
+	}
+
+	for _, subnet := range data.Subnets {
+		toScan, err := newToScan(subnet.CIDR, subnet.Site)
+		if err != nil {
+			return subnets, err
+		}
+		toScan.Exclude = subnet.Exclude
+		subnets = append(subnets, toScan)
+	}
+
+	return subnets, err
+}
+
+// LoadSubnetsFromCSV parses a list of subnets in the cidr,site format
+func LoadSubnetsFromCSV(content []byte) (subnets []*ToScan, err error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return subnets, err
+		}
+
+		toScan, err := newToScan(record[0], record[1])
+		if err != nil {
+			return subnets, err
+		}
+		subnets = append(subnets, toScan)
+	}
+
+	return subnets, nil
+}
+
+// newToScan validates the cidr and returns it normalized within a ToScan
+func newToScan(cidr string, site string) (*ToScan, error) {
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ToScan{
+		CIDR: ipNet.String(),
+		Site: strings.TrimSpace(site),
+	}, nil
+}
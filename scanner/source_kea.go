@@ -0,0 +1,394 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// maxKeaIncludeDepth protects us from include loops
+const maxKeaIncludeDepth = 10
+
+// Kea is the main entry for parsing the kea config file
+type Kea struct {
+	Dhcp4 *Dhcp `json:"Dhcp4"`
+	Dhcp6 *Dhcp `json:"Dhcp6"`
+}
+
+// Dhcp contains the dhcp information for ipv4 (Dhcp4) or ipv6 (Dhcp6) networks
+type Dhcp struct {
+	OptionData     []*OptionData    `json:"option-data"`
+	SharedNetworks []*SharedNetwork `json:"shared-networks"`
+	Subnet4        []*Subnet        `json:"subnet4"`
+	Subnet6        []*Subnet        `json:"subnet6"`
+	UserContext    UserContext      `json:"user-context"`
+}
+
+// SharedNetwork groups subnets sharing the same physical link, options and
+// user-context defined here are inherited by its subnets
+type SharedNetwork struct {
+	Name        string        `json:"name"`
+	OptionData  []*OptionData `json:"option-data"`
+	Subnet4     []*Subnet     `json:"subnet4"`
+	Subnet6     []*Subnet     `json:"subnet6"`
+	UserContext UserContext   `json:"user-context"`
+}
+
+// Subnet contains all the subnets managed by Kea, subnet4 and subnet6 entries
+// share the fields we care about
+type Subnet struct {
+	ID           int            `json:"id"`
+	OptionData   []*OptionData  `json:"option-data"`
+	Pools        []*Pool        `json:"pools"`
+	Reservations []*Reservation `json:"reservations"`
+	Subnet       string         `json:"subnet"`
+	UserContext  UserContext    `json:"user-context"`
+}
+
+// Pool is a dynamic range of a subnet, either first - last or a prefix
+type Pool struct {
+	Pool        string      `json:"pool"`
+	UserContext UserContext `json:"user-context"`
+}
+
+// Reservation is a host reservation within a subnet, ipv4 reservations use
+// ip-address and ipv6 reservations use ip-addresses
+type Reservation struct {
+	HwAddress   string      `json:"hw-address"`
+	Hostname    string      `json:"hostname"`
+	IPAddress   string      `json:"ip-address"`
+	IPAddresses []string    `json:"ip-addresses"`
+	UserContext UserContext `json:"user-context"`
+}
+
+// OptionData contains the options send to the clients during the dhcp request
+type OptionData struct {
+	Data string `json:"data"`
+	Name string `json:"name"`
+}
+
+// UserContext is the free form user-context map kea allows on most scopes
+type UserContext map[string]interface{}
+
+// KeaSource loads the subnets from the Kea dhcp4 and, optionally, dhcp6
+// config files. IPv6 subnets are never enumerated, so their hosts are seeded
+// from the reservations and the active leases of the dhcp6 memfile. With
+// LeasesOnly the ipv4 subnets are restricted to their active leases, read
+// from the lease database when LeaseDatabaseType is set or from the memfile
+type KeaSource struct {
+	Path                 string
+	Path6                string
+	Leases6Path          string
+	LeasesOnly           bool
+	Leases4Path          string
+	LeaseDatabaseType    string
+	LeaseDatabaseOptions string
+}
+
+// Name returns the name of the source
+func (k *KeaSource) Name() string {
+	return "kea"
+}
+
+// Subnets reads the Kea config files and returns the subnets found in them
+func (k *KeaSource) Subnets() (subnets []*ToScan, err error) {
+	for _, path := range []string{k.Path, k.Path6} {
+		if path == "" {
+			continue
+		}
+
+		content, err := ReadKeaConfig(path)
+		if err != nil {
+			return subnets, err
+		}
+
+		found, err := LoadSubnetsFromKea(content)
+		if err != nil {
+			return subnets, fmt.Errorf("%s: %w", path, err)
+		}
+		subnets = append(subnets, found...)
+	}
+
+	if k.Leases6Path != "" {
+		leases, err := LoadActiveLeases(k.Leases6Path)
+		if err != nil {
+			return subnets, err
+		}
+
+		var hosts []string
+		for _, lease := range leases {
+			hosts = append(hosts, lease.Address)
+		}
+		seedIPv6Hosts(subnets, hosts)
+	}
+
+	if k.LeasesOnly {
+		leases, err := k.leases4()
+		if err != nil {
+			return subnets, err
+		}
+		restrictToLeases(subnets, leases)
+	}
+
+	return subnets, nil
+}
+
+// leases4 returns the active dhcp4 leases
+func (k *KeaSource) leases4() (leases []*Lease, err error) {
+	if k.LeaseDatabaseType != "" {
+		return LoadActiveLeasesFromDB(k.LeaseDatabaseType, k.LeaseDatabaseOptions)
+	}
+	if k.Leases4Path != "" {
+		return LoadActiveLeases(k.Leases4Path)
+	}
+	return leases, errors.New("scanner.kea_leases_only needs scanner.kea_leases4 or scanner.kea_lease_database_type")
+}
+
+// ReadKeaConfig reads the kea config file, expanding all <?include "file"?>
+// directives and removing the comments so it can be parsed as plain json
+func ReadKeaConfig(path string) (content []byte, err error) {
+	return readKeaConfig(path, 0)
+}
+
+func readKeaConfig(path string, depth int) (content []byte, err error) {
+	if depth > maxKeaIncludeDepth {
+		return content, fmt.Errorf("kea config includes nested deeper than %d levels: %s", maxKeaIncludeDepth, path)
+	}
+
+	content, err = ioutil.ReadFile(path)
+	if err != nil {
+		return content, err
+	}
+
+	return preprocessKeaConfig(content, func(include string) ([]byte, error) {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		return readKeaConfig(include, depth+1)
+	})
+}
+
+// preprocessKeaConfig strips the //, # and /* */ comments kea accepts and
+// replaces the include directives with the content returned by include
+func preprocessKeaConfig(content []byte, include func(string) ([]byte, error)) ([]byte, error) {
+	var out bytes.Buffer
+	for i := 0; i < len(content); i++ {
+		switch {
+		case content[i] == '"':
+			end := i + 1
+			for ; end < len(content) && content[end] != '"'; end++ {
+				if content[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(content) {
+				return nil, fmt.Errorf("unterminated string in kea config")
+			}
+			out.Write(content[i : end+1])
+			i = end
+		case content[i] == '#', bytes.HasPrefix(content[i:], []byte("//")):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case bytes.HasPrefix(content[i:], []byte("/*")):
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment in kea config")
+			}
+			i += end + 3
+		case bytes.HasPrefix(content[i:], []byte("<?include")):
+			end := bytes.Index(content[i:], []byte("?>"))
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated include in kea config")
+			}
+			directive := bytes.TrimSpace(content[i+len("<?include") : i+end])
+			if len(directive) < 2 || directive[0] != '"' || directive[len(directive)-1] != '"' {
+				return nil, fmt.Errorf("invalid include in kea config: %s", content[i:i+end+2])
+			}
+			if include == nil {
+				return nil, fmt.Errorf("includes are not supported here: %s", directive)
+			}
+			included, err := include(string(directive[1 : len(directive)-1]))
+			if err != nil {
+				return nil, err
+			}
+			out.Write(included)
+			i += end + 1
+		default:
+			out.WriteByte(content[i])
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// LoadSubnetsFromKea from kea.cfg. The site of each subnet is taken from the
+// scanner.kea_site_key of its user-context, falling back to the domain-name
+// (or domain-search for ipv6) option. Subnets with scanner.kea_exclude_key set
+// in the user-context are skipped
+func LoadSubnetsFromKea(content []byte) (subnets []*ToScan, err error) {
+	content, err = preprocessKeaConfig(content, nil)
+	if err != nil {
+		return subnets, err
+	}
+
+	keaData := &Kea{}
+	if err = json.Unmarshal(content, &keaData); err != nil {
+		return subnets, fmt.Errorf("unable to parse kea config: %w", err)
+	}
+
+	if keaData.Dhcp4 == nil && keaData.Dhcp6 == nil {
+		return subnets, fmt.Errorf("kea config has no Dhcp4 or Dhcp6 section")
+	}
+
+	for _, dhcp := range []*Dhcp{keaData.Dhcp4, keaData.Dhcp6} {
+		if dhcp == nil {
+			continue
+		}
+
+		global := keaScope{}.inherit(dhcp.OptionData, dhcp.UserContext)
+		for _, subnet := range append(dhcp.Subnet4, dhcp.Subnet6...) {
+			if toScan := keaSubnet(subnet, global); toScan != nil {
+				subnets = append(subnets, toScan)
+			}
+		}
+
+		for _, sharedNetwork := range dhcp.SharedNetworks {
+			scope := global.inherit(sharedNetwork.OptionData, sharedNetwork.UserContext)
+			for _, subnet := range append(sharedNetwork.Subnet4, sharedNetwork.Subnet6...) {
+				if toScan := keaSubnet(subnet, scope); toScan != nil {
+					subnets = append(subnets, toScan)
+				}
+			}
+		}
+	}
+
+	return subnets, nil
+}
+
+// keaScope holds the values inherited from the enclosing scopes
+type keaScope struct {
+	domainNames []string
+	userContext UserContext
+}
+
+func (k keaScope) inherit(options []*OptionData, userContext UserContext) keaScope {
+	for _, option := range options {
+		switch option.Name {
+		case "domain-name":
+			k.domainNames = []string{option.Data}
+		case "domain-search":
+			// dhcpv6 has no domain-name option, the search list is the closest thing
+			k.domainNames = strings.FieldsFunc(option.Data, func(r rune) bool { return r == ',' || r == ' ' })
+		}
+	}
+
+	merged := UserContext{}
+	for key, value := range k.userContext {
+		merged[key] = value
+	}
+	for key, value := range userContext {
+		merged[key] = value
+	}
+	k.userContext = merged
+
+	return k
+}
+
+func (k keaScope) site() (site string, ok bool) {
+	site, ok = k.userContext[viper.GetString("scanner.kea_site_key")].(string)
+	if ok && site != "" {
+		return site, true
+	}
+
+	for _, domainName := range k.domainNames {
+		if site, ok = siteFromDomainName(domainName); ok {
+			return site, ok
+		}
+	}
+
+	return "", false
+}
+
+func keaSubnet(subnet *Subnet, parent keaScope) *ToScan {
+	scope := parent.inherit(subnet.OptionData, subnet.UserContext)
+
+	_, ipNet, err := net.ParseCIDR(subnet.Subnet)
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "subnet parsing", "subnet": subnet.Subnet}).Warn(err)
+		return nil
+	}
+
+	if exclude, ok := scope.userContext[viper.GetString("scanner.kea_exclude_key")].(bool); ok && exclude {
+		log.WithFields(log.Fields{"operation": "subnet parsing", "subnet": ipNet.String()}).Debug("subnet excluded by user-context")
+		return nil
+	}
+
+	site, ok := scope.site()
+	if !ok {
+		return nil
+	}
+
+	toScan := &ToScan{
+		CIDR:    ipNet.String(),
+		Site:    site,
+		Exclude: keaExclusions(subnet, scope),
+	}
+
+	if isIPv6(ipNet.IP) {
+		for _, reservation := range subnet.Reservations {
+			for _, address := range reservation.IPAddresses {
+				if ip := net.ParseIP(address); ip != nil && ipNet.Contains(ip) {
+					toScan.Hosts = append(toScan.Hosts, ip.String())
+				}
+			}
+		}
+	}
+
+	return toScan
+}
+
+// keaExclusions returns the exclusions listed under scanner.kea_exclude_hosts_key
+// in the user-context of the subnet or its enclosing scopes, along with the
+// reservations and pools having scanner.kea_exclude_key set
+func keaExclusions(subnet *Subnet, scope keaScope) (exclude []string) {
+	if entries, ok := scope.userContext[viper.GetString("scanner.kea_exclude_hosts_key")].([]interface{}); ok {
+		for _, entry := range entries {
+			if entry, ok := entry.(string); ok {
+				exclude = append(exclude, entry)
+			}
+		}
+	}
+
+	excluded := func(userContext UserContext) bool {
+		exclude, ok := userContext[viper.GetString("scanner.kea_exclude_key")].(bool)
+		return ok && exclude
+	}
+
+	for _, reservation := range subnet.Reservations {
+		if !excluded(reservation.UserContext) {
+			continue
+		}
+		if reservation.IPAddress != "" {
+			exclude = append(exclude, reservation.IPAddress)
+		}
+		exclude = append(exclude, reservation.IPAddresses...)
+	}
+
+	for _, pool := range subnet.Pools {
+		if excluded(pool.UserContext) {
+			exclude = append(exclude, strings.Replace(pool.Pool, " ", "", -1))
+		}
+	}
+
+	return exclude
+}
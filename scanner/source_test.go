@@ -0,0 +1,251 @@
+package scanner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestLoadSubnetsFromDhcpd(t *testing.T) {
+	content := []byte(`
+# global options
+option domain-name "example.com";
+
+subnet 192.168.64.0 netmask 255.255.255.0 {
+  option routers 192.168.64.1;
+  option domain-name "adc1.bmc.example.com";
+  pool {
+    range 192.168.64.10 192.168.64.200;
+  }
+}
+
+shared-network edc4 {
+  option domain-name "edc4.bmc.example.com";
+  subnet 192.168.17.0 netmask 255.255.255.0 { }
+  subnet 192.168.18.0 netmask 255.255.254.0 {
+    option domain-name "edc4.ext.example.com"; # not a bmc network
+  }
+}
+
+subnet 10.0.0.0 netmask 255.0.0.0 { }
+`)
+
+	viper.SetDefault("scanner.kea_domain_name_suffix", ".bmc.example.com")
+	subnets, err := LoadSubnetsFromDhcpd(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ToScan{
+		{CIDR: "192.168.64.0/24", Site: "adc1"},
+		{CIDR: "192.168.17.0/24", Site: "edc4"},
+	}
+	if !reflect.DeepEqual(subnets, expected) {
+		t.Errorf("expected %+v, found %+v", expected, subnets)
+	}
+
+	if _, err := LoadSubnetsFromDhcpd([]byte(`subnet 10.0.0.0 netmask 255.0.0.0 {`)); err == nil {
+		t.Errorf("expected an error for unbalanced braces")
+	}
+}
+
+func TestLoadSubnetsFromFile(t *testing.T) {
+	tt := []struct {
+		format  string
+		content []byte
+	}{
+		{
+			"yaml",
+			[]byte("subnets:\n  - cidr: 192.168.64.1/24\n    site: adc1\n  - cidr: 192.168.17.0/24\n    site: edc4\n"),
+		},
+		{
+			"csv",
+			[]byte("# cidr,site\n192.168.64.1/24,adc1\n192.168.17.0/24, edc4\n"),
+		},
+	}
+
+	expected := []*ToScan{
+		{CIDR: "192.168.64.0/24", Site: "adc1"},
+		{CIDR: "192.168.17.0/24", Site: "edc4"},
+	}
+
+	for _, tc := range tt {
+		var subnets []*ToScan
+		var err error
+		if tc.format == "yaml" {
+			subnets, err = LoadSubnetsFromYAML(tc.content)
+		} else {
+			subnets, err = LoadSubnetsFromCSV(tc.content)
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", tc.format, err)
+		}
+		if !reflect.DeepEqual(subnets, expected) {
+			t.Errorf("%s: expected %+v, found %+v", tc.format, expected, subnets)
+		}
+	}
+}
+
+func TestDirectorySource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"adc1.txt": "192.168.64.0/24\n\n# legacy\n192.168.65.0/24\n",
+		"edc4":     "192.168.17.0/24\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subnets, err := (&DirectorySource{Path: dir}).Subnets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ToScan{
+		{CIDR: "192.168.64.0/24", Site: "adc1"},
+		{CIDR: "192.168.65.0/24", Site: "adc1"},
+		{CIDR: "192.168.17.0/24", Site: "edc4"},
+	}
+	if !reflect.DeepEqual(subnets, expected) {
+		t.Errorf("expected %+v, found %+v", expected, subnets)
+	}
+}
+
+func TestNewSubnetSource(t *testing.T) {
+	for _, name := range []string{"kea", "file", "dhcpd", "directory"} {
+		source, err := NewSubnetSource(name)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if source.Name() != name {
+			t.Errorf("expected source %s, found %s", name, source.Name())
+		}
+	}
+
+	if _, err := NewSubnetSource("ldap"); err == nil {
+		t.Errorf("expected an error for an unknown subnet source")
+	}
+}
+
+func TestLoadActiveLeases(t *testing.T) {
+	f, err := ioutil.TempFile("", "kea-leases6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	fmt.Fprintf(f, "address,duid,valid_lifetime,expire,subnet_id,pref_lifetime,lease_type,iaid,prefix_len,fqdn_fwd,fqdn_rev,hostname,hwaddr,state\n")
+	fmt.Fprintf(f, "2001:db8::10,01:02,3600,%d,1,3000,0,1,128,0,0,bmc-1,aa:bb:cc:dd:ee:ff,0\n", future)
+	fmt.Fprintf(f, "2001:db8::11,01:03,3600,%d,1,3000,0,1,128,0,0,bmc-2,,0\n", past)
+	fmt.Fprintf(f, "2001:db8::12,01:04,3600,%d,1,3000,0,1,128,0,0,bmc-3,,0\n", future)
+	fmt.Fprintf(f, "2001:db8::12,01:04,3600,%d,1,3000,0,1,128,0,0,bmc-3,,2\n", future)
+	fmt.Fprintf(f, "2001:db8:1::,01:05,3600,%d,1,3000,2,1,64,0,0,,,0\n", future)
+	f.Close()
+
+	leases, err := LoadActiveLeases(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(leases) != 1 || leases[0].Address != "2001:db8::10" || leases[0].HwAddress != "aa:bb:cc:dd:ee:ff" || leases[0].Hostname != "bmc-1" {
+		t.Errorf("unexpected leases %+v", leases)
+	}
+}
+
+func TestKeaLeasesOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dora-kea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := `{"Dhcp4": {"subnet4": [
+		{"subnet": "192.168.64.0/24", "user-context": {"site": "adc1"}},
+		{"subnet": "192.168.65.0/24", "user-context": {"site": "adc1"}}
+	]}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "kea-dhcp4.conf"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	future := time.Now().Add(time.Hour).Unix()
+	leases := "address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state\n" +
+		fmt.Sprintf("192.168.64.10,aa:bb:cc:dd:ee:ff,,3600,%d,1,0,0,bmc-1,0\n", future) +
+		fmt.Sprintf("192.168.64.11,aa:bb:cc:dd:ee:00,,3600,%d,1,0,0,bmc-2,1\n", future) +
+		fmt.Sprintf("10.0.0.10,aa:bb:cc:dd:ee:01,,3600,%d,1,0,0,other,0\n", future)
+	if err := ioutil.WriteFile(filepath.Join(dir, "kea-leases4.csv"), []byte(leases), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.SetDefault("scanner.kea_site_key", "site")
+	source := &KeaSource{Path: filepath.Join(dir, "kea-dhcp4.conf"), LeasesOnly: true}
+	if _, err := source.Subnets(); err == nil {
+		t.Error("expected an error without any lease source")
+	}
+
+	source.Leases4Path = filepath.Join(dir, "kea-leases4.csv")
+	subnets, err := source.Subnets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(subnets) != 2 || !reflect.DeepEqual(subnets[0].Hosts, []string{"192.168.64.10"}) || !subnets[1].HostsOnly || len(subnets[1].Hosts) != 0 {
+		t.Fatalf("expected the subnets to be restricted to their leases, found %+v", subnets)
+	}
+
+	var ips []string
+	if err := eachTarget(subnets[1], func(ip string) { ips = append(ips, ip) }); err != nil || len(ips) != 0 {
+		t.Errorf("expected nothing to scan in a subnet without leases, found %v %v", ips, err)
+	}
+
+	db := testDB(t)
+	defer db.Close()
+
+	recordHosts(db, subnets[0])
+	host := model.Host{}
+	if err := db.Where("ip = ?", "192.168.64.10").First(&host).Error; err != nil {
+		t.Fatal(err)
+	}
+	if host.HwAddress != "aa:bb:cc:dd:ee:ff" || host.Hostname != "bmc-1" || host.Site != "adc1" || host.CIDR != "192.168.64.0/24" {
+		t.Errorf("unexpected host %+v", host)
+	}
+}
+
+func TestRestrictToLeases(t *testing.T) {
+	subnets := []*ToScan{
+		{CIDR: "192.168.64.0/24"},
+		{CIDR: "10.1.0.0/16"},
+		{CIDR: "2001:db8::/64"},
+	}
+	leases := []*Lease{
+		{Address: "192.168.64.10"},
+		{Address: "10.1.2.3"},
+		{Address: "10.2.0.1"},
+		{Address: "not an ip"},
+	}
+
+	restrictToLeases(subnets, leases)
+	if !reflect.DeepEqual(subnets[0].Hosts, []string{"192.168.64.10"}) || !reflect.DeepEqual(subnets[1].Hosts, []string{"10.1.2.3"}) {
+		t.Errorf("expected each lease in the subnet containing it, found %+v %+v", subnets[0], subnets[1])
+	}
+	if subnets[2].HostsOnly {
+		t.Errorf("expected the ipv6 subnet to be left alone, found %+v", subnets[2])
+	}
+}
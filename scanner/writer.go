@@ -0,0 +1,225 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+// batchRetryBackoff is multiplied by the attempt between two tries of a batch
+var batchRetryBackoff = time.Second
+
+// scannedPortColumns are written by the bulk upserts, id first
+var scannedPortColumns = []string{"id", "site", "cidr", "ip", "port", "protocol", "scanned_by", "state", "details", "stale", "updated_at"}
+
+// pendingPort is a scanned port waiting to be written with the subnet scan it belongs to
+type pendingPort struct {
+	sp   *model.ScannedPort
+	scan *subnetScan
+}
+
+// scanWriter buffers the scanned ports of the probes and upserts them in
+// batches instead of one statement per probe
+type scanWriter struct {
+	db        *gorm.DB
+	batchSize int
+	retries   int
+
+	mu      sync.Mutex
+	pending []*pendingPort
+	// writing is taken before mu is released so a flush waits for the
+	// batches already taken out of the buffer
+	writing sync.Mutex
+}
+
+func newScanWriter(db *gorm.DB) *scanWriter {
+	batchSize := viper.GetInt("scanner.db_batch_size")
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &scanWriter{
+		db:        db,
+		batchSize: batchSize,
+		retries:   viper.GetInt("scanner.db_batch_retries"),
+	}
+}
+
+// add buffers the scanned port and writes the batch once it is full
+func (w *scanWriter) add(scan *subnetScan, sp *model.ScannedPort) {
+	w.mu.Lock()
+	w.pending = append(w.pending, &pendingPort{sp: sp, scan: scan})
+	if len(w.pending) < w.batchSize {
+		w.mu.Unlock()
+		return
+	}
+
+	batch := w.pending
+	w.pending = nil
+	w.writing.Lock()
+	w.mu.Unlock()
+	defer w.writing.Unlock()
+
+	w.write(batch)
+}
+
+// flush writes everything buffered and waits for the batches being written
+func (w *scanWriter) flush() {
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.writing.Lock()
+	w.mu.Unlock()
+	defer w.writing.Unlock()
+
+	if len(batch) != 0 {
+		w.write(batch)
+	}
+}
+
+// write upserts the batch, retrying it when it fails, and records the port
+// events of the ports that changed state. The rows of a batch that can't be
+// written are counted as errors of their subnet scan
+func (w *scanWriter) write(batch []*pendingPort) {
+	// the same port scanned twice in a batch would make postgres refuse it
+	ports := make([]*model.ScannedPort, 0, len(batch))
+	index := make(map[string]int)
+	for _, p := range batch {
+		if i, ok := index[p.sp.ID]; ok {
+			ports[i] = p.sp
+			continue
+		}
+		index[p.sp.ID] = len(ports)
+		ports = append(ports, p.sp)
+	}
+
+	var err error
+	var previous map[string]string
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			log.WithFields(log.Fields{"operation": "storing scan", "rows": len(ports), "attempt": attempt}).Warn(err)
+			time.Sleep(time.Duration(attempt) * batchRetryBackoff)
+		}
+
+		if previous, err = previousStates(w.db, ports); err != nil {
+			continue
+		}
+		if err = upsertScannedPorts(w.db, probedPorts(ports, previous)); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		log.WithFields(log.Fields{"operation": "storing scan", "rows": len(ports)}).Error(err)
+		if viper.GetBool("metrics.enabled") {
+			metrics.IncrCounter([]string{"scan.db_save_failed"}, int64(len(ports)))
+		}
+		for _, p := range batch {
+			p.scan.mu.Lock()
+			p.scan.result.Errors++
+			p.scan.result.Error = err.Error()
+			p.scan.mu.Unlock()
+		}
+		return
+	}
+
+	for _, sp := range ports {
+		if state := previous[sp.ID]; state != "" && state != sp.State && sp.State != Result(notProbed).String() {
+			recordPortEvent(w.db, sp, state)
+		}
+	}
+}
+
+// previousStates returns the state of the ports already stored, by id
+func previousStates(db *gorm.DB, ports []*model.ScannedPort) (states map[string]string, err error) {
+	ids := make([]string, 0, len(ports))
+	for _, sp := range ports {
+		ids = append(ids, sp.ID)
+	}
+
+	var previous []model.ScannedPort
+	if err = db.Select("id, state").Where("id in (?)", ids).Find(&previous).Error; err != nil {
+		return states, err
+	}
+
+	states = make(map[string]string, len(previous))
+	for _, sp := range previous {
+		states[sp.ID] = sp.State
+	}
+	return states, nil
+}
+
+// probedPorts leaves out the ports of the hosts that didn't pass the liveness
+// check when they are already stored, they keep what the previous scans found
+func probedPorts(ports []*model.ScannedPort, previous map[string]string) []*model.ScannedPort {
+	probed := make([]*model.ScannedPort, 0, len(ports))
+	for _, sp := range ports {
+		if _, stored := previous[sp.ID]; stored && sp.State == Result(notProbed).String() {
+			continue
+		}
+		probed = append(probed, sp)
+	}
+	return probed
+}
+
+// upsertScannedPorts writes the ports in a single statement with the upsert
+// flavour of the database dialect
+func upsertScannedPorts(db *gorm.DB, ports []*model.ScannedPort) error {
+	if len(ports) == 0 {
+		return nil
+	}
+
+	dialect := db.Dialect()
+	columns := make([]string, 0, len(scannedPortColumns))
+	for _, column := range scannedPortColumns {
+		columns = append(columns, dialect.Quote(column))
+	}
+
+	query, err := upsertQuery(dialect.GetName(), dialect.Quote(db.NewScope(&model.ScannedPort{}).TableName()), columns, len(ports))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	values := make([]interface{}, 0, len(ports)*len(scannedPortColumns))
+	for _, sp := range ports {
+		sp.UpdatedAt = now
+		values = append(values, sp.ID, sp.Site, sp.CIDR, sp.IP, sp.Port, sp.Protocol, sp.ScannedBy, sp.State, sp.Details, sp.Stale, sp.UpdatedAt)
+	}
+
+	return db.Exec(query, values...).Error
+}
+
+// upsertQuery builds the bulk upsert of rows rows into table, the first
+// column being the primary key
+func upsertQuery(dialect string, table string, columns []string, rows int) (string, error) {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	placeholders := strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+	insert := fmt.Sprintf("INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), placeholders)
+
+	updates := make([]string, 0, len(columns)-1)
+	switch dialect {
+	case "postgres":
+		for _, column := range columns[1:] {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+		return fmt.Sprintf("INSERT %s ON CONFLICT (%s) DO UPDATE SET %s", insert, columns[0], strings.Join(updates, ", ")), nil
+	case "mysql":
+		for _, column := range columns[1:] {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", column, column))
+		}
+		return fmt.Sprintf("INSERT %s ON DUPLICATE KEY UPDATE %s", insert, strings.Join(updates, ", ")), nil
+	case "sqlite3":
+		return fmt.Sprintf("INSERT OR REPLACE %s", insert), nil
+	}
+
+	return "", fmt.Errorf("bulk upserts aren't supported by the %s dialect", dialect)
+}
@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/bmc-toolbox/dora/model"
+)
+
+func TestUpsertQuery(t *testing.T) {
+	tt := []struct {
+		dialect string
+		query   string
+	}{
+		{"postgres", `INSERT INTO t (id, state) VALUES (?, ?), (?, ?) ON CONFLICT (id) DO UPDATE SET state = EXCLUDED.state`},
+		{"mysql", `INSERT INTO t (id, state) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE state = VALUES(state)`},
+		{"sqlite3", `INSERT OR REPLACE INTO t (id, state) VALUES (?, ?), (?, ?)`},
+	}
+
+	for _, tc := range tt {
+		query, err := upsertQuery(tc.dialect, "t", []string{"id", "state"}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != tc.query {
+			t.Errorf("%s: expected %s, found %s", tc.dialect, tc.query, query)
+		}
+	}
+
+	if _, err := upsertQuery("mssql", "t", []string{"id", "state"}, 2); err == nil {
+		t.Error("expected an unsupported dialect to be refused")
+	}
+}
+
+func newTestPort(ip string, state string) *model.ScannedPort {
+	sp := &model.ScannedPort{IP: ip, CIDR: "10.0.0.0/24", Site: "adc1", Port: 443, Protocol: "tcp", ScannedBy: "test", State: state}
+	sp.ID = sp.GenID()
+	return sp
+}
+
+func TestScanWriter(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	scan := &subnetScan{result: &model.ScanRunSubnet{}}
+	w := &scanWriter{db: db, batchSize: 4}
+
+	stale := newTestPort("10.0.0.1", "open")
+	stale.Stale = true
+	if err := db.Create(stale).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w.add(scan, newTestPort("10.0.0.1", "closed"))
+	w.add(scan, newTestPort("10.0.0.2", "open"))
+
+	var count int
+	db.Model(&model.ScannedPort{}).Where("state = ?", "closed").Count(&count)
+	if count != 0 {
+		t.Fatalf("expected nothing written before the batch is full, found %d rows", count)
+	}
+
+	// the same port twice in a batch, the last result wins
+	w.add(scan, newTestPort("10.0.0.3", "closed"))
+	w.add(scan, newTestPort("10.0.0.3", "open"))
+	w.flush()
+
+	var ports []model.ScannedPort
+	if err := db.Order("ip").Find(&ports).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 3 {
+		t.Fatalf("expected 3 scanned ports, found %+v", ports)
+	}
+	if ports[0].State != "closed" || ports[0].Stale || ports[0].UpdatedAt.IsZero() {
+		t.Errorf("expected 10.0.0.1 to be refreshed as closed, found %+v", ports[0])
+	}
+	if ports[2].State != "open" {
+		t.Errorf("expected 10.0.0.3 to be open, found %+v", ports[2])
+	}
+
+	var events []model.PortEvent
+	if err := db.Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].IP != "10.0.0.1" || events[0].OldState != "open" || events[0].NewState != "closed" {
+		t.Errorf("expected a single open to closed event of 10.0.0.1, found %+v", events)
+	}
+
+	if scan.result.Errors != 0 {
+		t.Errorf("expected no error, found %+v", scan.result)
+	}
+}
+
+func TestScanWriterNotProbed(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	scan := &subnetScan{result: &model.ScanRunSubnet{}}
+	w := &scanWriter{db: db, batchSize: 4}
+
+	if err := db.Create(newTestPort("10.0.0.1", "open")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// neither host passed the liveness check
+	w.add(scan, newTestPort("10.0.0.1", Result(notProbed).String()))
+	w.add(scan, newTestPort("10.0.0.2", Result(notProbed).String()))
+	w.flush()
+
+	var ports []model.ScannedPort
+	if err := db.Order("ip").Find(&ports).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 2 || ports[0].State != "open" || ports[1].State != "not_probed" {
+		t.Errorf("expected 10.0.0.1 to keep its state and 10.0.0.2 to be not probed, found %+v", ports)
+	}
+
+	var events int
+	db.Model(&model.PortEvent{}).Count(&events)
+	if events != 0 {
+		t.Errorf("expected no port event for hosts that weren't probed, found %d", events)
+	}
+}
+
+func TestScanWriterFailedBatch(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SingularTable(true)
+
+	backoff := batchRetryBackoff
+	batchRetryBackoff = 0
+	defer func() { batchRetryBackoff = backoff }()
+
+	scan := &subnetScan{result: &model.ScanRunSubnet{}}
+	w := &scanWriter{db: db, batchSize: 10, retries: 2}
+	w.add(scan, newTestPort("10.0.0.1", "open"))
+	w.add(scan, newTestPort("10.0.0.2", "open"))
+	w.flush()
+
+	if scan.result.Errors != 2 || scan.result.Error == "" {
+		t.Errorf("expected both rows of the batch to be counted as errors, found %+v", scan.result)
+	}
+}
@@ -47,6 +47,15 @@ func InitDB() *gorm.DB {
 		&model.Disk{},
 		&model.Fan{},
 		&model.DiscoverHint{},
+		&model.IpmiFingerprint{},
+		&model.HTTPFingerprint{},
+		&model.Certificate{},
+		&model.ScanRun{},
+		&model.ScanRunSubnet{},
+		&model.PortEvent{},
+		&model.CollectionAttempt{},
+		&model.CircuitBreaker{},
+		&model.Host{},
 	)
 
 	return db
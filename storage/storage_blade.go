@@ -217,3 +217,23 @@ func (b *BladeStorage) RemoveOldRefs(blade *model.Blade) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// GetAllByNetworks retrieve the blades whose bmc_address is within the given networks
+func (b BladeStorage) GetAllByNetworks(offset string, limit string, cidrs []string) (count int, blades []model.Blade, err error) {
+	serials, err := serialsWithin(b.db, &model.Blade{}, cidrs)
+	if err != nil || len(serials) == 0 {
+		return count, blades, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = b.db.Limit(limit).Offset(offset).Where("serial in (?)", serials).Order("serial asc").Find(&blades).Error; err != nil {
+			return count, blades, err
+		}
+		count = len(serials)
+	} else {
+		if err = b.db.Where("serial in (?)", serials).Order("serial asc").Find(&blades).Error; err != nil {
+			return count, blades, err
+		}
+	}
+	return count, blades, err
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewCertificateStorage initializes the storage
+func NewCertificateStorage(db *gorm.DB) *CertificateStorage {
+	return &CertificateStorage{db}
+}
+
+// CertificateStorage stores all Certificates
+type CertificateStorage struct {
+	db *gorm.DB
+}
+
+// Count gets Certificates count based on the filter
+func (c CertificateStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.Certificate{}, c.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.Certificate{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the Certificates
+func (c CertificateStorage) GetAll(offset string, limit string) (count int, certificates []model.Certificate, err error) {
+	if offset != "" && limit != "" {
+		if err = c.db.Limit(limit).Offset(offset).Order("ip").Find(&certificates).Error; err != nil {
+			return count, certificates, err
+		}
+		c.db.Model(&model.Certificate{}).Order("ip").Count(&count)
+	} else {
+		if err = c.db.Order("ip").Find(&certificates).Error; err != nil {
+			return count, certificates, err
+		}
+	}
+	return count, certificates, c.linkDevices(certificates)
+}
+
+// GetAllByFilters get all Certificates based on the filter
+func (c CertificateStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, certificates []model.Certificate, err error) {
+	q, err := filters.BuildQuery(model.Certificate{}, c.db)
+	if err != nil {
+		return count, certificates, err
+	}
+
+	return c.find(q, offset, limit)
+}
+
+// GetAllExpiringBefore get all Certificates matching the filter and expiring before the deadline
+func (c CertificateStorage) GetAllExpiringBefore(offset string, limit string, deadline time.Time, filters *filter.Filters) (count int, certificates []model.Certificate, err error) {
+	q, err := filters.BuildQuery(model.Certificate{}, c.db)
+	if err != nil {
+		return count, certificates, err
+	}
+
+	return c.find(q.Where("not_after <= ?", deadline).Order("not_after"), offset, limit)
+}
+
+// GetAllByScannedPortsID retrieve the Certificates of the given scanned ports
+func (c CertificateStorage) GetAllByScannedPortsID(offset string, limit string, ids []string) (count int, certificates []model.Certificate, err error) {
+	return c.find(c.db.Where("scanned_port_id in (?)", ids), offset, limit)
+}
+
+// GetAllByChassisID retrieve the Certificates presented by the bmc of the given chassis
+func (c CertificateStorage) GetAllByChassisID(offset string, limit string, serials []string) (count int, certificates []model.Certificate, err error) {
+	return c.find(c.db.Joins("INNER JOIN chassis ON chassis.bmc_address = certificate.ip").Where("chassis.serial in (?)", serials), offset, limit)
+}
+
+// GetAllByBladesID retrieve the Certificates presented by the bmc of the given blades
+func (c CertificateStorage) GetAllByBladesID(offset string, limit string, serials []string) (count int, certificates []model.Certificate, err error) {
+	return c.find(c.db.Joins("INNER JOIN blade ON blade.bmc_address = certificate.ip").Where("blade.serial in (?)", serials), offset, limit)
+}
+
+// GetAllByDiscretesID retrieve the Certificates presented by the bmc of the given discretes
+func (c CertificateStorage) GetAllByDiscretesID(offset string, limit string, serials []string) (count int, certificates []model.Certificate, err error) {
+	return c.find(c.db.Joins("INNER JOIN discrete ON discrete.bmc_address = certificate.ip").Where("discrete.serial in (?)", serials), offset, limit)
+}
+
+// GetOne Certificate
+func (c CertificateStorage) GetOne(id string) (certificate model.Certificate, err error) {
+	if err := c.db.Where("scanned_port_id = ?", id).First(&certificate).Error; err != nil {
+		return certificate, err
+	}
+
+	certificates := []model.Certificate{certificate}
+	err = c.linkDevices(certificates)
+	return certificates[0], err
+}
+
+// find runs the query with the requested pagination and links the results to their devices
+func (c CertificateStorage) find(q *gorm.DB, offset string, limit string) (count int, certificates []model.Certificate, err error) {
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Find(&certificates).Error; err != nil {
+			return count, certificates, err
+		}
+		q.Model(&model.Certificate{}).Count(&count)
+	} else {
+		if err = q.Find(&certificates).Error; err != nil {
+			return count, certificates, err
+		}
+	}
+
+	return count, certificates, c.linkDevices(certificates)
+}
+
+// linkDevices fills the serial of the chassis, blade or discrete whose bmc
+// answers on the ip of each certificate
+func (c CertificateStorage) linkDevices(certificates []model.Certificate) (err error) {
+	if len(certificates) == 0 {
+		return nil
+	}
+
+	ips := make([]string, 0, len(certificates))
+	for _, certificate := range certificates {
+		ips = append(ips, certificate.IP)
+	}
+
+	var chassis []model.Chassis
+	if err = c.db.Select("serial, bmc_address").Where("bmc_address in (?)", ips).Find(&chassis).Error; err != nil {
+		return err
+	}
+	var blades []model.Blade
+	if err = c.db.Select("serial, bmc_address").Where("bmc_address in (?)", ips).Find(&blades).Error; err != nil {
+		return err
+	}
+	var discretes []model.Discrete
+	if err = c.db.Select("serial, bmc_address").Where("bmc_address in (?)", ips).Find(&discretes).Error; err != nil {
+		return err
+	}
+
+	for i := range certificates {
+		for _, ch := range chassis {
+			if ch.BmcAddress == certificates[i].IP {
+				certificates[i].ChassisSerial = ch.Serial
+			}
+		}
+		for _, bl := range blades {
+			if bl.BmcAddress == certificates[i].IP {
+				certificates[i].BladeSerial = bl.Serial
+			}
+		}
+		for _, d := range discretes {
+			if d.BmcAddress == certificates[i].IP {
+				certificates[i].DiscreteSerial = d.Serial
+			}
+		}
+	}
+
+	return nil
+}
@@ -299,3 +299,23 @@ func (c *ChassisStorage) RemoveOldRefs(chassis *model.Chassis) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// GetAllByNetworks retrieve the chassis whose bmc_address is within the given networks
+func (c ChassisStorage) GetAllByNetworks(offset string, limit string, cidrs []string) (count int, chassis []model.Chassis, err error) {
+	serials, err := serialsWithin(c.db, &model.Chassis{}, cidrs)
+	if err != nil || len(serials) == 0 {
+		return count, chassis, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = c.db.Limit(limit).Offset(offset).Where("serial in (?)", serials).Order("serial asc").Find(&chassis).Error; err != nil {
+			return count, chassis, err
+		}
+		count = len(serials)
+	} else {
+		if err = c.db.Where("serial in (?)", serials).Order("serial asc").Find(&chassis).Error; err != nil {
+			return count, chassis, err
+		}
+	}
+	return count, chassis, err
+}
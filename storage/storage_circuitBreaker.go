@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewCircuitBreakerStorage initializes the storage
+func NewCircuitBreakerStorage(db *gorm.DB) *CircuitBreakerStorage {
+	return &CircuitBreakerStorage{db}
+}
+
+// CircuitBreakerStorage stores all CircuitBreakers
+type CircuitBreakerStorage struct {
+	db *gorm.DB
+}
+
+// Count gets CircuitBreakers count based on the filter
+func (c CircuitBreakerStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.CircuitBreaker{}, c.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.CircuitBreaker{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the CircuitBreakers
+func (c CircuitBreakerStorage) GetAll(offset string, limit string) (count int, breakers []model.CircuitBreaker, err error) {
+	return c.find(c.db, offset, limit)
+}
+
+// GetAllByFilters get all CircuitBreakers based on the filter
+func (c CircuitBreakerStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, breakers []model.CircuitBreaker, err error) {
+	q, err := filters.BuildQuery(model.CircuitBreaker{}, c.db)
+	if err != nil {
+		return count, breakers, err
+	}
+
+	return c.find(q, offset, limit)
+}
+
+// GetOne CircuitBreaker
+func (c CircuitBreakerStorage) GetOne(ip string) (breaker model.CircuitBreaker, err error) {
+	if err := c.db.Where("ip = ?", ip).First(&breaker).Error; err != nil {
+		return breaker, err
+	}
+	return breaker, err
+}
+
+// find runs the query with the requested pagination, the most failing bmcs first
+func (c CircuitBreakerStorage) find(q *gorm.DB, offset string, limit string) (count int, breakers []model.CircuitBreaker, err error) {
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("failures desc, ip").Find(&breakers).Error; err != nil {
+			return count, breakers, err
+		}
+		q.Model(&model.CircuitBreaker{}).Count(&count)
+	} else {
+		if err = q.Order("failures desc, ip").Find(&breakers).Error; err != nil {
+			return count, breakers, err
+		}
+	}
+	return count, breakers, err
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewCollectionAttemptStorage initializes the storage
+func NewCollectionAttemptStorage(db *gorm.DB) *CollectionAttemptStorage {
+	return &CollectionAttemptStorage{db}
+}
+
+// CollectionAttemptStorage stores all CollectionAttempts
+type CollectionAttemptStorage struct {
+	db *gorm.DB
+}
+
+// Count gets CollectionAttempts count based on the filter
+func (c CollectionAttemptStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.CollectionAttempt{}, c.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.CollectionAttempt{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the CollectionAttempts, the most recent first
+func (c CollectionAttemptStorage) GetAll(offset string, limit string) (count int, attempts []model.CollectionAttempt, err error) {
+	return c.find(c.db, offset, limit)
+}
+
+// GetAllByFilters get all CollectionAttempts based on the filter
+func (c CollectionAttemptStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, attempts []model.CollectionAttempt, err error) {
+	q, err := filters.BuildQuery(model.CollectionAttempt{}, c.db)
+	if err != nil {
+		return count, attempts, err
+	}
+
+	return c.find(q, offset, limit)
+}
+
+// GetAllByChassisID retrieve the CollectionAttempts of the given chassis
+func (c CollectionAttemptStorage) GetAllByChassisID(offset string, limit string, serials []string) (count int, attempts []model.CollectionAttempt, err error) {
+	return c.find(c.db.Where("asset_type = ? and asset_serial in (?)", "chassis", serials), offset, limit)
+}
+
+// GetAllByBladesID retrieve the CollectionAttempts of the given blades
+func (c CollectionAttemptStorage) GetAllByBladesID(offset string, limit string, serials []string) (count int, attempts []model.CollectionAttempt, err error) {
+	return c.find(c.db.Where("asset_type = ? and asset_serial in (?)", "blades", serials), offset, limit)
+}
+
+// GetAllByDiscretesID retrieve the CollectionAttempts of the given discretes
+func (c CollectionAttemptStorage) GetAllByDiscretesID(offset string, limit string, serials []string) (count int, attempts []model.CollectionAttempt, err error) {
+	return c.find(c.db.Where("asset_type = ? and asset_serial in (?)", "discretes", serials), offset, limit)
+}
+
+// GetOne CollectionAttempt
+func (c CollectionAttemptStorage) GetOne(id string) (attempt model.CollectionAttempt, err error) {
+	if err := c.db.Where("id = ?", id).First(&attempt).Error; err != nil {
+		return attempt, err
+	}
+	return attempt, err
+}
+
+// find runs the query with the requested pagination, the most recent attempts first
+func (c CollectionAttemptStorage) find(q *gorm.DB, offset string, limit string) (count int, attempts []model.CollectionAttempt, err error) {
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+		q.Model(&model.CollectionAttempt{}).Count(&count)
+	} else {
+		if err = q.Order("started_at desc").Find(&attempts).Error; err != nil {
+			return count, attempts, err
+		}
+	}
+	return count, attempts, err
+}
@@ -224,3 +224,23 @@ func (d *DiscreteStorage) RemoveOldRefs(discrete *model.Discrete) (err error) {
 	merror = multierror.Append(merror, err)
 	return merror.ErrorOrNil()
 }
+
+// GetAllByNetworks retrieve the discretes whose bmc_address is within the given networks
+func (d DiscreteStorage) GetAllByNetworks(offset string, limit string, cidrs []string) (count int, discretes []model.Discrete, err error) {
+	serials, err := serialsWithin(d.db, &model.Discrete{}, cidrs)
+	if err != nil || len(serials) == 0 {
+		return count, discretes, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = d.db.Limit(limit).Offset(offset).Where("serial in (?)", serials).Order("serial asc").Find(&discretes).Error; err != nil {
+			return count, discretes, err
+		}
+		count = len(serials)
+	} else {
+		if err = d.db.Where("serial in (?)", serials).Order("serial asc").Find(&discretes).Error; err != nil {
+			return count, discretes, err
+		}
+	}
+	return count, discretes, err
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewHostStorage initializes the storage
+func NewHostStorage(db *gorm.DB) *HostStorage {
+	return &HostStorage{db}
+}
+
+// HostStorage stores all Hosts
+type HostStorage struct {
+	db *gorm.DB
+}
+
+// Count gets Hosts count based on the filter
+func (s HostStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.Host{}, s.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.Host{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the Hosts
+func (s HostStorage) GetAll(offset string, limit string) (count int, hosts []model.Host, err error) {
+
+	query := s.db.Offset(offset).Limit(limit)
+
+	if err = query.Order("ip").Find(&hosts).Error; err != nil {
+		return count, hosts, err
+	}
+	query.Model(&model.Host{}).Order("ip").Count(&count)
+	return count, hosts, err
+}
+
+// GetAllByFilters get all Hosts based on the filter
+func (s HostStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, hosts []model.Host, err error) {
+	query, err := filters.BuildQuery(model.Host{}, s.db)
+	if err != nil {
+		return count, hosts, err
+	}
+
+	query = query.Offset(offset).Limit(limit)
+
+	if err = query.Order("ip").Find(&hosts).Error; err != nil {
+		return count, hosts, err
+	}
+	query.Model(&model.Host{}).Order("ip").Count(&count)
+
+	return count, hosts, err
+}
+
+// GetOne Host
+func (s HostStorage) GetOne(id string) (host model.Host, err error) {
+	if err := s.db.Where("ip = ?", id).First(&host).Error; err != nil {
+		return host, err
+	}
+	return host, err
+}
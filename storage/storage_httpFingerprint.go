@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewHTTPFingerprintStorage initializes the storage
+func NewHTTPFingerprintStorage(db *gorm.DB) *HTTPFingerprintStorage {
+	return &HTTPFingerprintStorage{db}
+}
+
+// HTTPFingerprintStorage stores all HTTPFingerprints
+type HTTPFingerprintStorage struct {
+	db *gorm.DB
+}
+
+// Count gets HTTPFingerprints count based on the filter
+func (h HTTPFingerprintStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.HTTPFingerprint{}, h.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.HTTPFingerprint{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the HTTPFingerprints
+func (h HTTPFingerprintStorage) GetAll(offset string, limit string) (count int, fingerprints []model.HTTPFingerprint, err error) {
+	if offset != "" && limit != "" {
+		if err = h.db.Limit(limit).Offset(offset).Order("ip").Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		h.db.Model(&model.HTTPFingerprint{}).Order("ip").Count(&count)
+	} else {
+		if err = h.db.Order("ip").Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+	return count, fingerprints, err
+}
+
+// GetAllByFilters get all HTTPFingerprints based on the filter
+func (h HTTPFingerprintStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, fingerprints []model.HTTPFingerprint, err error) {
+	q, err := filters.BuildQuery(model.HTTPFingerprint{}, h.db)
+	if err != nil {
+		return count, fingerprints, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		q.Model(&model.HTTPFingerprint{}).Count(&count)
+	} else {
+		if err = q.Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	return count, fingerprints, err
+}
+
+// GetAllByScannedPortsID retrieve the HTTPFingerprints of the given scanned ports
+func (h HTTPFingerprintStorage) GetAllByScannedPortsID(offset string, limit string, ids []string) (count int, fingerprints []model.HTTPFingerprint, err error) {
+	if offset != "" && limit != "" {
+		if err = h.db.Limit(limit).Offset(offset).Where("scanned_port_id in (?)", ids).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		h.db.Model(&model.HTTPFingerprint{}).Where("scanned_port_id in (?)", ids).Count(&count)
+	} else {
+		if err = h.db.Where("scanned_port_id in (?)", ids).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+	return count, fingerprints, err
+}
+
+// GetOne HTTPFingerprint
+func (h HTTPFingerprintStorage) GetOne(id string) (fingerprint model.HTTPFingerprint, err error) {
+	if err := h.db.Where("scanned_port_id = ?", id).First(&fingerprint).Error; err != nil {
+		return fingerprint, err
+	}
+	return fingerprint, err
+}
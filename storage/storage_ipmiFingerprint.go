@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewIpmiFingerprintStorage initializes the storage
+func NewIpmiFingerprintStorage(db *gorm.DB) *IpmiFingerprintStorage {
+	return &IpmiFingerprintStorage{db}
+}
+
+// IpmiFingerprintStorage stores all IpmiFingerprints
+type IpmiFingerprintStorage struct {
+	db *gorm.DB
+}
+
+// Count gets IpmiFingerprints count based on the filter
+func (i IpmiFingerprintStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.IpmiFingerprint{}, i.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.IpmiFingerprint{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the IpmiFingerprints
+func (i IpmiFingerprintStorage) GetAll(offset string, limit string) (count int, fingerprints []model.IpmiFingerprint, err error) {
+	if offset != "" && limit != "" {
+		if err = i.db.Limit(limit).Offset(offset).Order("ip").Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		i.db.Model(&model.IpmiFingerprint{}).Order("ip").Count(&count)
+	} else {
+		if err = i.db.Order("ip").Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+	return count, fingerprints, err
+}
+
+// GetAllByFilters get all IpmiFingerprints based on the filter
+func (i IpmiFingerprintStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, fingerprints []model.IpmiFingerprint, err error) {
+	q, err := filters.BuildQuery(model.IpmiFingerprint{}, i.db)
+	if err != nil {
+		return count, fingerprints, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		q.Model(&model.IpmiFingerprint{}).Count(&count)
+	} else {
+		if err = q.Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+
+	return count, fingerprints, err
+}
+
+// GetAllByScannedPortsID retrieve the IpmiFingerprints of the given scanned ports
+func (i IpmiFingerprintStorage) GetAllByScannedPortsID(offset string, limit string, ids []string) (count int, fingerprints []model.IpmiFingerprint, err error) {
+	if offset != "" && limit != "" {
+		if err = i.db.Limit(limit).Offset(offset).Where("scanned_port_id in (?)", ids).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+		i.db.Model(&model.IpmiFingerprint{}).Where("scanned_port_id in (?)", ids).Count(&count)
+	} else {
+		if err = i.db.Where("scanned_port_id in (?)", ids).Find(&fingerprints).Error; err != nil {
+			return count, fingerprints, err
+		}
+	}
+	return count, fingerprints, err
+}
+
+// GetOne IpmiFingerprint
+func (i IpmiFingerprintStorage) GetOne(id string) (fingerprint model.IpmiFingerprint, err error) {
+	if err := i.db.Where("scanned_port_id = ?", id).First(&fingerprint).Error; err != nil {
+		return fingerprint, err
+	}
+	return fingerprint, err
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewPortEventStorage initializes the storage
+func NewPortEventStorage(db *gorm.DB) *PortEventStorage {
+	return &PortEventStorage{db}
+}
+
+// PortEventStorage stores all PortEvents
+type PortEventStorage struct {
+	db *gorm.DB
+}
+
+// Count gets PortEvents count based on the filter
+func (p PortEventStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.PortEvent{}, p.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.PortEvent{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the PortEvents
+func (p PortEventStorage) GetAll(offset string, limit string) (count int, events []model.PortEvent, err error) {
+	if offset != "" && limit != "" {
+		if err = p.db.Limit(limit).Offset(offset).Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+		p.db.Model(&model.PortEvent{}).Order("changed_at desc").Count(&count)
+	} else {
+		if err = p.db.Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+	}
+	return count, events, err
+}
+
+// GetAllByFilters get all PortEvents based on the filter
+func (p PortEventStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, events []model.PortEvent, err error) {
+	q, err := filters.BuildQuery(model.PortEvent{}, p.db)
+	if err != nil {
+		return count, events, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+		q.Model(&model.PortEvent{}).Count(&count)
+	} else {
+		if err = q.Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+	}
+
+	return count, events, err
+}
+
+// GetAllByScannedPortsID retrieve the PortEvents of the given scanned ports
+func (p PortEventStorage) GetAllByScannedPortsID(offset string, limit string, ids []string) (count int, events []model.PortEvent, err error) {
+	if offset != "" && limit != "" {
+		if err = p.db.Limit(limit).Offset(offset).Where("scanned_port_id in (?)", ids).Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+		p.db.Model(&model.PortEvent{}).Where("scanned_port_id in (?)", ids).Count(&count)
+	} else {
+		if err = p.db.Where("scanned_port_id in (?)", ids).Order("changed_at desc").Find(&events).Error; err != nil {
+			return count, events, err
+		}
+	}
+	return count, events, err
+}
+
+// GetOne PortEvent
+func (p PortEventStorage) GetOne(id string) (event model.PortEvent, err error) {
+	if err := p.db.Where("id = ?", id).First(&event).Error; err != nil {
+		return event, err
+	}
+	return event, err
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewScanRunStorage initializes the storage
+func NewScanRunStorage(db *gorm.DB) *ScanRunStorage {
+	return &ScanRunStorage{db}
+}
+
+// ScanRunStorage stores all ScanRuns
+type ScanRunStorage struct {
+	db *gorm.DB
+}
+
+// Count gets ScanRuns count based on the filter
+func (s ScanRunStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.ScanRun{}, s.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.ScanRun{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the ScanRuns, the most recent first
+func (s ScanRunStorage) GetAll(offset string, limit string) (count int, runs []model.ScanRun, err error) {
+	return s.find(s.db, offset, limit)
+}
+
+// GetAllByFilters get all ScanRuns based on the filter
+func (s ScanRunStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, runs []model.ScanRun, err error) {
+	q, err := filters.BuildQuery(model.ScanRun{}, s.db)
+	if err != nil {
+		return count, runs, err
+	}
+
+	return s.find(q, offset, limit)
+}
+
+// GetAllByScanRunSubnetsID retrieve the ScanRuns of the given subnet scans
+func (s ScanRunStorage) GetAllByScanRunSubnetsID(offset string, limit string, ids []string) (count int, runs []model.ScanRun, err error) {
+	return s.find(s.db.Where("id in (select scan_run_id from scan_run_subnet where id in (?))", ids), offset, limit)
+}
+
+// GetAllByCIDR retrieve the ScanRuns that included the given networks
+func (s ScanRunStorage) GetAllByCIDR(offset string, limit string, cidrs []string) (count int, runs []model.ScanRun, err error) {
+	return s.find(s.db.Where("id in (select scan_run_id from scan_run_subnet where cidr in (?))", cidrs), offset, limit)
+}
+
+// GetAllBySite retrieve the ScanRuns that included networks of the given sites
+func (s ScanRunStorage) GetAllBySite(offset string, limit string, sites []string) (count int, runs []model.ScanRun, err error) {
+	return s.find(s.db.Where("id in (select scan_run_id from scan_run_subnet where site in (?))", sites), offset, limit)
+}
+
+// GetOne ScanRun
+func (s ScanRunStorage) GetOne(id string) (run model.ScanRun, err error) {
+	if err := s.db.Where("id = ?", id).Preload("ScanRunSubnets").First(&run).Error; err != nil {
+		return run, err
+	}
+
+	runs := []model.ScanRun{run}
+	summarize(runs)
+	return runs[0], err
+}
+
+// find runs the query with the requested pagination and summarizes the results
+func (s ScanRunStorage) find(q *gorm.DB, offset string, limit string) (count int, runs []model.ScanRun, err error) {
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Order("started_at desc").Preload("ScanRunSubnets").Find(&runs).Error; err != nil {
+			return count, runs, err
+		}
+		q.Model(&model.ScanRun{}).Count(&count)
+	} else {
+		if err = q.Order("started_at desc").Preload("ScanRunSubnets").Find(&runs).Error; err != nil {
+			return count, runs, err
+		}
+	}
+
+	summarize(runs)
+	return count, runs, err
+}
+
+// summarize fills the per profile counters of the runs out of their subnets
+func summarize(runs []model.ScanRun) {
+	for i := range runs {
+		runs[i].Profiles = make(map[string]*model.ProfileStat)
+		for _, subnet := range runs[i].ScanRunSubnets {
+			stat, ok := runs[i].Profiles[subnet.Profile]
+			if !ok {
+				stat = &model.ProfileStat{}
+				runs[i].Profiles[subnet.Profile] = stat
+			}
+			stat.HostsProbed += subnet.HostsProbed
+			stat.OpenPorts += subnet.OpenPorts
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewScanRunSubnetStorage initializes the storage
+func NewScanRunSubnetStorage(db *gorm.DB) *ScanRunSubnetStorage {
+	return &ScanRunSubnetStorage{db}
+}
+
+// ScanRunSubnetStorage stores all ScanRunSubnets
+type ScanRunSubnetStorage struct {
+	db *gorm.DB
+}
+
+// Count gets ScanRunSubnets count based on the filter
+func (s ScanRunSubnetStorage) Count(filters *filter.Filters) (count int, err error) {
+	q, err := filters.BuildQuery(model.ScanRunSubnet{}, s.db)
+	if err != nil {
+		return count, err
+	}
+
+	err = q.Model(&model.ScanRunSubnet{}).Count(&count).Error
+	return count, err
+}
+
+// GetAll of the ScanRunSubnets
+func (s ScanRunSubnetStorage) GetAll(offset string, limit string) (count int, subnets []model.ScanRunSubnet, err error) {
+	if offset != "" && limit != "" {
+		if err = s.db.Limit(limit).Offset(offset).Order("cidr").Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+		s.db.Model(&model.ScanRunSubnet{}).Order("cidr").Count(&count)
+	} else {
+		if err = s.db.Order("cidr").Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+	}
+	return count, subnets, err
+}
+
+// GetAllByFilters get all ScanRunSubnets based on the filter
+func (s ScanRunSubnetStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, subnets []model.ScanRunSubnet, err error) {
+	q, err := filters.BuildQuery(model.ScanRunSubnet{}, s.db)
+	if err != nil {
+		return count, subnets, err
+	}
+
+	if offset != "" && limit != "" {
+		if err = q.Limit(limit).Offset(offset).Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+		q.Model(&model.ScanRunSubnet{}).Count(&count)
+	} else {
+		if err = q.Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+	}
+
+	return count, subnets, err
+}
+
+// GetAllByScanRunsID retrieve the ScanRunSubnets of the given scan runs
+func (s ScanRunSubnetStorage) GetAllByScanRunsID(offset string, limit string, ids []string) (count int, subnets []model.ScanRunSubnet, err error) {
+	if offset != "" && limit != "" {
+		if err = s.db.Limit(limit).Offset(offset).Where("scan_run_id in (?)", ids).Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+		s.db.Model(&model.ScanRunSubnet{}).Where("scan_run_id in (?)", ids).Count(&count)
+	} else {
+		if err = s.db.Where("scan_run_id in (?)", ids).Find(&subnets).Error; err != nil {
+			return count, subnets, err
+		}
+	}
+	return count, subnets, err
+}
+
+// GetOne ScanRunSubnet
+func (s ScanRunSubnetStorage) GetOne(id string) (subnet model.ScanRunSubnet, err error) {
+	if err := s.db.Where("id = ?", id).First(&subnet).Error; err != nil {
+		return subnet, err
+	}
+	return subnet, err
+}
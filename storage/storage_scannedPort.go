@@ -1,11 +1,21 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/bmc-toolbox/dora/filter"
 	"github.com/bmc-toolbox/dora/model"
 	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
 )
 
+// scannedPortRelations are the fields preloading the relations a request can include
+var scannedPortRelations = map[string]string{
+	"ipmi_fingerprints": "IpmiFingerprint",
+	"http_fingerprints": "HTTPFingerprint",
+	"certificates":      "Certificate",
+}
+
 // NewScannedPortStorage initializes the storage
 func NewScannedPortStorage(db *gorm.DB) *ScannedPortStorage {
 	return &ScannedPortStorage{db}
@@ -28,45 +38,77 @@ func (s ScannedPortStorage) Count(filters *filter.Filters) (count int, err error
 }
 
 // GetAll of the ScannedPorts
-func (s ScannedPortStorage) GetAll(offset string, limit string) (count int, ports []model.ScannedPort, err error) {
-	if offset != "" && limit != "" {
-		if err = s.db.Limit(limit).Offset(offset).Order("cidr").Find(&ports).Error; err != nil {
-			return count, ports, err
-		}
-		s.db.Model(&model.ScannedPort{}).Order("cidr").Count(&count)
-	} else {
-		if err = s.db.Order("cidr").Find(&ports).Error; err != nil {
-			return count, ports, err
-		}
-	}
-	return count, ports, err
+func (s ScannedPortStorage) GetAll(offset string, limit string, include []string) (count int, ports []model.ScannedPort, err error) {
+	return s.find(s.db.Order("cidr"), offset, limit, include)
 }
 
 // GetAllByFilters get all chassis based on the filter
-func (s ScannedPortStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, ports []model.ScannedPort, err error) {
+func (s ScannedPortStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters, include []string) (count int, ports []model.ScannedPort, err error) {
 	q, err := filters.BuildQuery(model.ScannedPort{}, s.db)
 	if err != nil {
 		return count, ports, err
 	}
+	return s.find(q, offset, limit, include)
+}
+
+// GetOne Host
+func (s ScannedPortStorage) GetOne(id string) (scan model.ScannedPort, err error) {
+	if err := s.db.Where("id = ?", id).Preload("IpmiFingerprint").Preload("HTTPFingerprint").Preload("Certificate").First(&scan).Error; err != nil {
+		return scan, err
+	}
+	return scan, err
+}
+
+// GetAllByIpmiFingerprintsID retrieve the scanned ports of the given ipmi fingerprints
+func (s ScannedPortStorage) GetAllByIpmiFingerprintsID(offset string, limit string, ids []string, include []string) (count int, ports []model.ScannedPort, err error) {
+	return s.find(s.db.Where("id in (?)", ids), offset, limit, include)
+}
+
+// GetAllByHTTPFingerprintsID retrieve the scanned ports of the given http fingerprints
+func (s ScannedPortStorage) GetAllByHTTPFingerprintsID(offset string, limit string, ids []string, include []string) (count int, ports []model.ScannedPort, err error) {
+	return s.find(s.db.Where("id in (?)", ids), offset, limit, include)
+}
+
+// GetAllByCertificatesID retrieve the scanned ports of the given certificates
+func (s ScannedPortStorage) GetAllByCertificatesID(offset string, limit string, ids []string, include []string) (count int, ports []model.ScannedPort, err error) {
+	return s.find(s.db.Where("id in (?)", ids), offset, limit, include)
+}
+
+// GetAllBySubnetsID retrieve the scanned ports of the given subnets
+func (s ScannedPortStorage) GetAllBySubnetsID(offset string, limit string, ids []string, include []string) (count int, ports []model.ScannedPort, err error) {
+	var cidrs []string
+	for _, id := range ids {
+		cidrs = append(cidrs, model.SubnetCIDR(id))
+	}
+	return s.find(s.db.Where("cidr in (?)", cidrs).Order("ip"), offset, limit, include)
+}
+
+// GetAllBySitesID retrieve the scanned ports of the given sites
+func (s ScannedPortStorage) GetAllBySitesID(offset string, limit string, sites []string, include []string) (count int, ports []model.ScannedPort, err error) {
+	return s.find(s.db.Where("site in (?)", sites).Order("cidr"), offset, limit, include)
+}
+
+// find runs the query of the scanned ports, a page of them when offset and
+// limit are given, and only preloads the relations the request includes
+func (s ScannedPortStorage) find(q *gorm.DB, offset string, limit string, include []string) (count int, ports []model.ScannedPort, err error) {
+	found := q
+	for _, relation := range include {
+		field, ok := scannedPortRelations[relation]
+		if !ok {
+			return count, ports, api2go.NewHTTPError(nil, fmt.Sprintf("invalid include: %s", relation), 422)
+		}
+		found = found.Preload(field)
+	}
 
 	if offset != "" && limit != "" {
-		if err = q.Limit(limit).Offset(offset).Find(&ports).Error; err != nil {
+		if err = found.Limit(limit).Offset(offset).Find(&ports).Error; err != nil {
 			return count, ports, err
 		}
 		q.Model(&model.ScannedPort{}).Count(&count)
 	} else {
-		if err = q.Find(&ports).Error; err != nil {
+		if err = found.Find(&ports).Error; err != nil {
 			return count, ports, err
 		}
 	}
-
 	return count, ports, err
 }
-
-// GetOne Host
-func (s ScannedPortStorage) GetOne(id string) (scan model.ScannedPort, err error) {
-	if err := s.db.Where("id = ?", id).First(&scan).Error; err != nil {
-		return scan, err
-	}
-	return scan, err
-}
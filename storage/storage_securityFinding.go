@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewSecurityFindingStorage initializes the storage
+func NewSecurityFindingStorage(db *gorm.DB) *SecurityFindingStorage {
+	return &SecurityFindingStorage{db}
+}
+
+// SecurityFindingStorage serves the SecurityFindings computed out of the
+// collected assets and the scan data
+type SecurityFindingStorage struct {
+	db *gorm.DB
+}
+
+// GetAll of the SecurityFindings
+func (s SecurityFindingStorage) GetAll(offset string, limit string) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(model.SecurityFinding) (bool, error) { return true, nil })
+}
+
+// GetAllByFilters get all SecurityFindings based on the filter
+func (s SecurityFindingStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(finding model.SecurityFinding) (bool, error) { return filters.Match(finding) })
+}
+
+// GetAllByScannedPortsID retrieve the SecurityFindings of the given scanned ports
+func (s SecurityFindingStorage) GetAllByScannedPortsID(offset string, limit string, ids []string) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(finding model.SecurityFinding) (bool, error) { return contains(ids, finding.ScannedPortID), nil })
+}
+
+// GetAllByChassisID retrieve the SecurityFindings of the bmc of the given chassis
+func (s SecurityFindingStorage) GetAllByChassisID(offset string, limit string, serials []string) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(finding model.SecurityFinding) (bool, error) {
+		return contains(serials, finding.ChassisSerial), nil
+	})
+}
+
+// GetAllByBladesID retrieve the SecurityFindings of the bmc of the given blades
+func (s SecurityFindingStorage) GetAllByBladesID(offset string, limit string, serials []string) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(finding model.SecurityFinding) (bool, error) { return contains(serials, finding.BladeSerial), nil })
+}
+
+// GetAllByDiscretesID retrieve the SecurityFindings of the bmc of the given discretes
+func (s SecurityFindingStorage) GetAllByDiscretesID(offset string, limit string, serials []string) (count int, findings []model.SecurityFinding, err error) {
+	return s.matching(offset, limit, func(finding model.SecurityFinding) (bool, error) {
+		return contains(serials, finding.DiscreteSerial), nil
+	})
+}
+
+// GetOne SecurityFinding
+func (s SecurityFindingStorage) GetOne(id string) (finding model.SecurityFinding, err error) {
+	all, err := s.findings()
+	if err != nil {
+		return finding, err
+	}
+
+	for _, finding := range all {
+		if finding.ID == id {
+			return finding, nil
+		}
+	}
+	return finding, gorm.ErrRecordNotFound
+}
+
+// matching returns the requested page of the findings accepted by match
+func (s SecurityFindingStorage) matching(offset string, limit string, match func(model.SecurityFinding) (bool, error)) (count int, findings []model.SecurityFinding, err error) {
+	all, err := s.findings()
+	if err != nil {
+		return count, findings, err
+	}
+
+	var filtered []model.SecurityFinding
+	for _, finding := range all {
+		ok, err := match(finding)
+		if err != nil {
+			return count, findings, err
+		}
+		if ok {
+			filtered = append(filtered, finding)
+		}
+	}
+
+	start, end, err := pageBounds(len(filtered), offset, limit)
+	if err != nil {
+		return count, findings, err
+	}
+	return len(filtered), filtered[start:end], nil
+}
+
+// securityAsset is what a finding needs to know of the chassis, blade or
+// discrete whose bmc it's about
+type securityAsset struct {
+	kind         string
+	serial       string
+	vendor       string
+	model        string
+	authMethod   string
+	credentialID string
+	updatedAt    time.Time
+}
+
+// findings lists the bmcs that accepted their vendor default credentials,
+// the ipmi ports accepting anonymous sessions and the https ports presenting
+// an expired certificate, leaving out the stale scanned ports
+func (s SecurityFindingStorage) findings() (findings []model.SecurityFinding, err error) {
+	assets := make(map[string][]securityAsset)
+	for _, asset := range []struct {
+		kind  string
+		model interface{}
+	}{
+		{"chassis", &model.Chassis{}},
+		{"blades", &model.Blade{}},
+		{"discretes", &model.Discrete{}},
+	} {
+		rows, err := s.db.Model(asset.model).Select("serial, bmc_address, vendor, model, coalesce(auth_method, ''), coalesce(credential_id, ''), updated_at").Where("bmc_address <> ''").Rows()
+		if err != nil {
+			return findings, err
+		}
+		for rows.Next() {
+			a := securityAsset{kind: asset.kind}
+			var address string
+			if err = rows.Scan(&a.serial, &address, &a.vendor, &a.model, &a.authMethod, &a.credentialID, &a.updatedAt); err != nil {
+				rows.Close()
+				return findings, err
+			}
+			assets[address] = append(assets[address], a)
+		}
+		rows.Close()
+	}
+
+	for address, list := range assets {
+		for _, a := range list {
+			if a.authMethod != model.AuthDefaultVendor {
+				continue
+			}
+			finding := model.SecurityFinding{
+				ID:         fmt.Sprintf("%s-%s-%s", model.FindingDefaultCredentials, a.kind, a.serial),
+				Type:       model.FindingDefaultCredentials,
+				Severity:   "high",
+				IP:         address,
+				Detail:     fmt.Sprintf("logged in with the vendor default credentials %s", a.credentialID),
+				DetectedAt: a.updatedAt,
+			}
+			linkAsset(&finding, a)
+			findings = append(findings, finding)
+		}
+	}
+
+	var fingerprints []model.IpmiFingerprint
+	if err = s.db.Joins("INNER JOIN scanned_port ON scanned_port.id = ipmi_fingerprint.scanned_port_id").
+		Where("scanned_port.stale = ? and (ipmi_fingerprint.anonymous_login = ? or ipmi_fingerprint.null_usernames = ?)", false, true, true).
+		Find(&fingerprints).Error; err != nil {
+		return findings, err
+	}
+	for _, fingerprint := range fingerprints {
+		detail := "anonymous login enabled"
+		if !fingerprint.AnonymousLogin {
+			detail = "null usernames enabled"
+		}
+		finding := model.SecurityFinding{
+			ID:            fmt.Sprintf("%s-%s", model.FindingAnonymousIpmi, fingerprint.ScannedPortID),
+			Type:          model.FindingAnonymousIpmi,
+			Severity:      "high",
+			IP:            fingerprint.IP,
+			Port:          fingerprint.Port,
+			Detail:        detail,
+			DetectedAt:    fingerprint.UpdatedAt,
+			ScannedPortID: fingerprint.ScannedPortID,
+		}
+		for _, a := range assets[fingerprint.IP] {
+			linkAsset(&finding, a)
+		}
+		findings = append(findings, finding)
+	}
+
+	var certificates []model.Certificate
+	if err = s.db.Joins("INNER JOIN scanned_port ON scanned_port.id = certificate.scanned_port_id").
+		Where("scanned_port.stale = ? and certificate.not_after < ?", false, time.Now()).
+		Find(&certificates).Error; err != nil {
+		return findings, err
+	}
+	for _, certificate := range certificates {
+		finding := model.SecurityFinding{
+			ID:            fmt.Sprintf("%s-%s", model.FindingExpiredCertificate, certificate.ScannedPortID),
+			Type:          model.FindingExpiredCertificate,
+			Severity:      "medium",
+			IP:            certificate.IP,
+			Port:          certificate.Port,
+			Detail:        fmt.Sprintf("%s expired on %s", certificate.Subject, certificate.NotAfter.Format("2006-01-02")),
+			DetectedAt:    certificate.UpdatedAt,
+			ScannedPortID: certificate.ScannedPortID,
+		}
+		for _, a := range assets[certificate.IP] {
+			linkAsset(&finding, a)
+		}
+		findings = append(findings, finding)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Type != findings[j].Type {
+			return findings[i].Type < findings[j].Type
+		}
+		return findings[i].ID < findings[j].ID
+	})
+	return findings, nil
+}
+
+// linkAsset fills the serial, vendor and model of the asset in the finding
+func linkAsset(finding *model.SecurityFinding, a securityAsset) {
+	switch a.kind {
+	case "chassis":
+		finding.ChassisSerial = a.serial
+	case "blades":
+		finding.BladeSerial = a.serial
+	case "discretes":
+		finding.DiscreteSerial = a.serial
+	}
+	finding.Vendor = a.vendor
+	finding.Model = a.model
+}
+
+func contains(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+)
+
+// NewSiteStorage initializes the storage
+func NewSiteStorage(subnets *SubnetStorage) *SiteStorage {
+	return &SiteStorage{subnets}
+}
+
+// SiteStorage serves the Sites of the subnet source, summed up out of their Subnets
+type SiteStorage struct {
+	subnets *SubnetStorage
+}
+
+// GetAll of the Sites
+func (s SiteStorage) GetAll(offset string, limit string) (count int, sites []model.Site, err error) {
+	all, err := s.sites()
+	if err != nil {
+		return count, sites, err
+	}
+	return paginateSites(all, offset, limit)
+}
+
+// GetAllByFilters get all Sites based on the filter
+func (s SiteStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, sites []model.Site, err error) {
+	all, err := s.sites()
+	if err != nil {
+		return count, sites, err
+	}
+
+	var filtered []model.Site
+	for _, site := range all {
+		ok, err := filters.Match(site)
+		if err != nil {
+			return count, sites, err
+		}
+		if ok {
+			filtered = append(filtered, site)
+		}
+	}
+	return paginateSites(filtered, offset, limit)
+}
+
+// GetAllBySubnetsID retrieve the Sites of the given subnets
+func (s SiteStorage) GetAllBySubnetsID(offset string, limit string, ids []string) (count int, sites []model.Site, err error) {
+	all, err := s.sites()
+	if err != nil {
+		return count, sites, err
+	}
+
+	var filtered []model.Site
+	for _, site := range all {
+	cidrs:
+		for _, cidr := range site.CIDRs {
+			for _, id := range ids {
+				if cidr == model.SubnetCIDR(id) {
+					filtered = append(filtered, site)
+					break cidrs
+				}
+			}
+		}
+	}
+	return paginateSites(filtered, offset, limit)
+}
+
+// GetOne Site
+func (s SiteStorage) GetOne(name string) (site model.Site, err error) {
+	all, err := s.sites()
+	if err != nil {
+		return site, err
+	}
+
+	for _, site := range all {
+		if site.Name == name {
+			return site, nil
+		}
+	}
+	return site, gorm.ErrRecordNotFound
+}
+
+// sites groups the subnets by site and sums up their counters
+func (s SiteStorage) sites() (sites []model.Site, err error) {
+	subnets, err := s.subnets.subnets()
+	if err != nil {
+		return sites, err
+	}
+
+	index := make(map[string]int)
+	for _, subnet := range subnets {
+		i, ok := index[subnet.Site]
+		if !ok {
+			i = len(sites)
+			index[subnet.Site] = i
+			sites = append(sites, model.Site{Name: subnet.Site})
+		}
+
+		site := &sites[i]
+		site.Subnets++
+		site.HostsWithOpenPorts += subnet.HostsWithOpenPorts
+		site.BmcsCollected += subnet.BmcsCollected
+		if subnet.LastScanAt != nil && (site.LastScanAt == nil || subnet.LastScanAt.After(*site.LastScanAt)) {
+			site.LastScanAt = subnet.LastScanAt
+		}
+		site.CIDRs = append(site.CIDRs, subnet.CIDR)
+	}
+
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites, nil
+}
+
+// paginateSites returns the requested page of the sites and the total count
+func paginateSites(sites []model.Site, offset string, limit string) (count int, page []model.Site, err error) {
+	start, end, err := pageBounds(len(sites), offset, limit)
+	if err != nil {
+		return count, page, err
+	}
+	return len(sites), sites[start:end], nil
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bmc-toolbox/dora/filter"
+	"github.com/bmc-toolbox/dora/model"
+	"github.com/jinzhu/gorm"
+	"github.com/manyminds/api2go"
+)
+
+// SubnetLoader returns the cidr and site of each network of the subnet source
+type SubnetLoader func() ([]*model.Subnet, error)
+
+// NewSubnetStorage initializes the storage
+func NewSubnetStorage(db *gorm.DB, load SubnetLoader) *SubnetStorage {
+	return &SubnetStorage{db, load}
+}
+
+// SubnetStorage serves the Subnets of the subnet source with their counters
+type SubnetStorage struct {
+	db   *gorm.DB
+	load SubnetLoader
+}
+
+// GetAll of the Subnets
+func (s SubnetStorage) GetAll(offset string, limit string) (count int, subnets []model.Subnet, err error) {
+	all, err := s.subnets()
+	if err != nil {
+		return count, subnets, err
+	}
+	return paginate(all, offset, limit)
+}
+
+// GetAllByFilters get all Subnets based on the filter
+func (s SubnetStorage) GetAllByFilters(offset string, limit string, filters *filter.Filters) (count int, subnets []model.Subnet, err error) {
+	all, err := s.subnets()
+	if err != nil {
+		return count, subnets, err
+	}
+
+	var filtered []model.Subnet
+	for _, subnet := range all {
+		ok, err := filters.Match(subnet)
+		if err != nil {
+			return count, subnets, err
+		}
+		if ok {
+			filtered = append(filtered, subnet)
+		}
+	}
+	return paginate(filtered, offset, limit)
+}
+
+// GetAllBySitesID retrieve the Subnets of the given sites
+func (s SubnetStorage) GetAllBySitesID(offset string, limit string, sites []string) (count int, subnets []model.Subnet, err error) {
+	all, err := s.subnets()
+	if err != nil {
+		return count, subnets, err
+	}
+
+	var filtered []model.Subnet
+	for _, subnet := range all {
+		for _, site := range sites {
+			if subnet.Site == site {
+				filtered = append(filtered, subnet)
+			}
+		}
+	}
+	return paginate(filtered, offset, limit)
+}
+
+// GetOne Subnet, by its id or cidr
+func (s SubnetStorage) GetOne(id string) (subnet model.Subnet, err error) {
+	all, err := s.subnets()
+	if err != nil {
+		return subnet, err
+	}
+
+	for _, subnet := range all {
+		if subnet.CIDR == model.SubnetCIDR(id) {
+			return subnet, nil
+		}
+	}
+	return subnet, gorm.ErrRecordNotFound
+}
+
+// CIDRsOfSites returns the networks of the given sites
+func (s SubnetStorage) CIDRsOfSites(sites []string) (cidrs []string, err error) {
+	_, subnets, err := s.GetAllBySitesID("", "", sites)
+	for _, subnet := range subnets {
+		cidrs = append(cidrs, subnet.CIDR)
+	}
+	return cidrs, err
+}
+
+// subnets loads the subnet source and computes the counters of each network:
+// the addresses with open ports, the assets whose bmc_address was scanned
+// within it and the end of its latest finished scan
+func (s SubnetStorage) subnets() (subnets []model.Subnet, err error) {
+	loaded, err := s.load()
+	if err != nil {
+		return subnets, err
+	}
+
+	for _, subnet := range loaded {
+		subnets = append(subnets, *subnet)
+	}
+	index := make(map[string]*model.Subnet)
+	for i := range subnets {
+		index[subnets[i].CIDR] = &subnets[i]
+	}
+
+	err = countByCIDR(s.db.Model(&model.ScannedPort{}).Select("cidr, count(distinct ip)").Where("state = ? and stale = ?", "open", false).Group("cidr"), func(cidr string, hosts int) {
+		if subnet, ok := index[cidr]; ok {
+			subnet.HostsWithOpenPorts = hosts
+		}
+	})
+	if err != nil {
+		return subnets, err
+	}
+
+	for _, asset := range []string{"chassis", "blade", "discrete"} {
+		q := s.db.Model(&model.ScannedPort{}).
+			Select(fmt.Sprintf("scanned_port.cidr, count(distinct %s.serial)", asset)).
+			Joins(fmt.Sprintf("inner join %s on %s.bmc_address = scanned_port.ip", asset, asset)).
+			Group("scanned_port.cidr")
+		err = countByCIDR(q, func(cidr string, assets int) {
+			if subnet, ok := index[cidr]; ok {
+				subnet.BmcsCollected += assets
+			}
+		})
+		if err != nil {
+			return subnets, err
+		}
+	}
+
+	rows, err := s.db.Model(&model.ScanRunSubnet{}).
+		Select("scan_run_subnet.cidr, scan_run_subnet.finished_at").
+		Joins("inner join (select cidr, max(finished_at) as finished_at from scan_run_subnet where state = ? group by cidr) latest on latest.cidr = scan_run_subnet.cidr and latest.finished_at = scan_run_subnet.finished_at", model.ScanRunFinished).
+		Rows()
+	if err != nil {
+		return subnets, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cidr string
+		var finishedAt time.Time
+		if err = rows.Scan(&cidr, &finishedAt); err != nil {
+			return subnets, err
+		}
+		if subnet, ok := index[cidr]; ok {
+			subnet.LastScanAt = &finishedAt
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return subnets, err
+	}
+
+	sort.Slice(subnets, func(i, j int) bool { return subnets[i].CIDR < subnets[j].CIDR })
+	return subnets, nil
+}
+
+// countByCIDR runs a query grouped by cidr returning the cidr and a count
+func countByCIDR(q *gorm.DB, add func(cidr string, count int)) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cidr string
+		var count int
+		if err = rows.Scan(&cidr, &count); err != nil {
+			return err
+		}
+		add(cidr, count)
+	}
+	return rows.Err()
+}
+
+// serialsWithin returns the serial of the assets of the model whose
+// bmc_address was scanned within one of the networks
+func serialsWithin(db *gorm.DB, m interface{}, cidrs []string) (serials []string, err error) {
+	scanned := db.Model(&model.ScannedPort{}).Select("ip").Where("cidr in (?)", cidrs).SubQuery()
+	err = db.Model(m).Where("bmc_address in ?", scanned).Order("serial asc").Pluck("serial", &serials).Error
+	return serials, err
+}
+
+// paginate returns the requested page of the items and the total count
+func paginate(subnets []model.Subnet, offset string, limit string) (count int, page []model.Subnet, err error) {
+	start, end, err := pageBounds(len(subnets), offset, limit)
+	if err != nil {
+		return count, page, err
+	}
+	return len(subnets), subnets[start:end], nil
+}
+
+// pageBounds translates page[offset] and page[limit] into slice bounds, a
+// limit of 0 being no limit
+func pageBounds(total int, offset string, limit string) (start int, end int, err error) {
+	end = total
+	if offset != "" {
+		if start, err = strconv.Atoi(offset); err != nil || start < 0 {
+			return start, end, api2go.NewHTTPError(nil, fmt.Sprintf("invalid page[offset]: %s", offset), 400)
+		}
+	}
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return start, end, api2go.NewHTTPError(nil, fmt.Sprintf("invalid page[limit]: %s", limit), 400)
+		}
+		if l > 0 && start+l < total {
+			end = start + l
+		}
+	}
+	if start > total {
+		start = total
+	}
+	return start, end, nil
+}
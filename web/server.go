@@ -2,11 +2,14 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -31,6 +34,8 @@ import (
 
 type scanRequest struct {
 	Networks []string `json:"networks"`
+	// Site of the networks that aren't in the subnet source, to scan them ad hoc
+	Site string `json:"site"`
 }
 
 type collectionRequest struct {
@@ -85,6 +90,28 @@ func RunGin(port int, rodb bool, debug bool) {
 	diskStorage := storage.NewDiskStorage(db)
 	fanStorage := storage.NewFanStorage(db)
 	discoverHintStorage := storage.NewDiscoverHintStorage(db)
+	ipmiFingerprintStorage := storage.NewIpmiFingerprintStorage(db)
+	httpFingerprintStorage := storage.NewHTTPFingerprintStorage(db)
+	certificateStorage := storage.NewCertificateStorage(db)
+	scanRunStorage := storage.NewScanRunStorage(db)
+	scanRunSubnetStorage := storage.NewScanRunSubnetStorage(db)
+	portEventStorage := storage.NewPortEventStorage(db)
+	hostStorage := storage.NewHostStorage(db)
+	// the subnet source is reloaded once older than api.source_cache_ttl or on SIGHUP
+	source := scanner.NewSourceCache(viper.GetDuration("api.source_cache_ttl"))
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.WithFields(log.Fields{"operation": "loading subnets"}).Info("reloading the subnet source")
+			source.Reset()
+		}
+	}()
+	subnetStorage := storage.NewSubnetStorage(db, source.Subnets)
+	siteStorage := storage.NewSiteStorage(subnetStorage)
+	securityFindingStorage := storage.NewSecurityFindingStorage(db)
+	collectionAttemptStorage := storage.NewCollectionAttemptStorage(db)
+	circuitBreakerStorage := storage.NewCircuitBreakerStorage(db)
 
 	stats := stats.Stats{StartTime: time.Now()}
 
@@ -116,11 +143,12 @@ func RunGin(port int, rodb bool, debug bool) {
 		scannedPortStorage,
 		psuStorage,
 		diskStorage,
-		fanStorage)
+		fanStorage,
+		discoverHintStorage)
 
-	api.AddResource(model.Chassis{}, resource.ChassisResource{ChassisStorage: chassisStorage})
-	api.AddResource(model.Blade{}, resource.BladeResource{BladeStorage: bladeStorage})
-	api.AddResource(model.Discrete{}, resource.DiscreteResource{DiscreteStorage: discreteStorage})
+	api.AddResource(model.Chassis{}, resource.ChassisResource{ChassisStorage: chassisStorage, SubnetStorage: subnetStorage})
+	api.AddResource(model.Blade{}, resource.BladeResource{BladeStorage: bladeStorage, SubnetStorage: subnetStorage})
+	api.AddResource(model.Discrete{}, resource.DiscreteResource{DiscreteStorage: discreteStorage, SubnetStorage: subnetStorage})
 	api.AddResource(model.StorageBlade{}, resource.StorageBladeResource{StorageBladeStorage: storageBladeStorage})
 	api.AddResource(model.Nic{}, resource.NicResource{NicStorage: nicStorage})
 	api.AddResource(model.ScannedPort{}, resource.ScannedPortResource{ScannedPortStorage: scannedPortStorage})
@@ -128,6 +156,18 @@ func RunGin(port int, rodb bool, debug bool) {
 	api.AddResource(model.Disk{}, resource.DiskResource{DiskStorage: diskStorage})
 	api.AddResource(model.Fan{}, resource.FanResource{FanStorage: fanStorage})
 	api.AddResource(model.DiscoverHint{}, resource.DiscoverHintResource{DiscoverHintStorage: discoverHintStorage})
+	api.AddResource(model.IpmiFingerprint{}, resource.IpmiFingerprintResource{IpmiFingerprintStorage: ipmiFingerprintStorage})
+	api.AddResource(model.HTTPFingerprint{}, resource.HTTPFingerprintResource{HTTPFingerprintStorage: httpFingerprintStorage})
+	api.AddResource(model.Certificate{}, resource.CertificateResource{CertificateStorage: certificateStorage})
+	api.AddResource(model.ScanRun{}, resource.ScanRunResource{ScanRunStorage: scanRunStorage})
+	api.AddResource(model.ScanRunSubnet{}, resource.ScanRunSubnetResource{ScanRunSubnetStorage: scanRunSubnetStorage})
+	api.AddResource(model.PortEvent{}, resource.PortEventResource{PortEventStorage: portEventStorage})
+	api.AddResource(model.Host{}, resource.HostResource{HostStorage: hostStorage})
+	api.AddResource(model.Subnet{}, resource.SubnetResource{SubnetStorage: subnetStorage})
+	api.AddResource(model.Site{}, resource.SiteResource{SiteStorage: siteStorage})
+	api.AddResource(model.SecurityFinding{}, resource.SecurityFindingResource{SecurityFindingStorage: securityFindingStorage})
+	api.AddResource(model.CollectionAttempt{}, resource.CollectionAttemptResource{CollectionAttemptStorage: collectionAttemptStorage})
+	api.AddResource(model.CircuitBreaker{}, resource.CircuitBreakerResource{CircuitBreakerStorage: circuitBreakerStorage})
 
 	r.POST("/api/v1/collect", func(c *gin.Context) {
 		subject := "dora::collect"
@@ -139,11 +179,23 @@ func RunGin(port int, rodb bool, debug bool) {
 				c.JSON(http.StatusPreconditionFailed, gin.H{"message": fmt.Sprintf("publisher unable to connect: %v", err)})
 				return
 			}
+			exclusions, err := source.ExclusionChecker()
+			if err != nil {
+				log.WithFields(log.Fields{"subject": subject, "operation": "loading exclusions"}).Error(err)
+				c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+				return
+			}
 			for _, ip := range jsonPayload.Ips {
 				if net.ParseIP(ip) == nil {
 					c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid ip: %s", ip)})
 					return
 				}
+				if exclusions.Excluded(ip) {
+					c.JSON(http.StatusForbidden, gin.H{"message": fmt.Sprintf("excluded ip: %s", ip)})
+					return
+				}
+			}
+			for _, ip := range jsonPayload.Ips {
 				nc.Publish(subject, []byte(ip))
 				nc.Flush()
 				if err := nc.LastError(); err != nil {
@@ -169,9 +221,8 @@ func RunGin(port int, rodb bool, debug bool) {
 		jsonPayload := &scanRequest{}
 		var response []gin.H
 		if err := c.ShouldBindWith(&jsonPayload, binding.JSON); err == nil {
-			nc, err := nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
-			if err != nil {
-				c.JSON(http.StatusPreconditionFailed, gin.H{"message": fmt.Sprintf("publisher unable to connect: %v", err)})
+			if len(jsonPayload.Networks) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"message": "no networks to scan"})
 				return
 			}
 			for _, network := range jsonPayload.Networks {
@@ -180,9 +231,39 @@ func RunGin(port int, rodb bool, debug bool) {
 					c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid network: %s", network)})
 					return
 				}
+			}
+
+			toScan, err := scanner.ResolveSubnets(jsonPayload.Networks, viper.GetStringSlice("site"), jsonPayload.Site)
+			switch {
+			case errors.Is(err, scanner.ErrNotInSubnetSource), errors.Is(err, scanner.ErrOverlappingNetworks):
+				c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+				return
+			case errors.Is(err, scanner.ErrAdHocNotAllowed):
+				c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+				return
+			case err != nil:
+				log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": subject, "operation": "loading subnets"}).Error(err)
+				c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+				return
+			}
+
+			nc, err := nats.Connect(viper.GetString("collector.worker.server"), nats.UserInfo(viper.GetString("collector.worker.username"), viper.GetString("collector.worker.password")))
+			if err != nil {
+				c.JSON(http.StatusPreconditionFailed, gin.H{"message": fmt.Sprintf("publisher unable to connect: %v", err)})
+				return
+			}
+
+			// the scan doesn't depend on the run, on a read only database the workers track it themselves
+			var runID string
+			run, err := scanner.NewScanRun(db, scanner.TriggeredByAPI, toScan)
+			if err != nil {
+				log.WithFields(log.Fields{"subject": subject, "operation": "creating scan run"}).Warn(err)
+			} else {
+				runID = run.ID
+			}
 
-				subnets := scanner.LoadSubnets(viper.GetString("scanner.subnet_source"), []string{network}, viper.GetStringSlice("site"))
-				subnet := subnets[0]
+			for _, subnet := range toScan {
+				network := subnet.CIDR
 				s, err := json.Marshal(subnet)
 				if err != nil {
 					log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": subject, "operation": "encoding subnet"}).Error(err)
@@ -194,11 +275,14 @@ func RunGin(port int, rodb bool, debug bool) {
 				nc.Flush()
 				if err := nc.LastError(); err != nil {
 					log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": subject, "payload": s}).Error(err)
-					response = append(response, gin.H{"network": network, "error": err.Error()})
+					if run != nil {
+						scanner.FailScanRun(db, run, err)
+					}
+					response = append(response, gin.H{"network": network, "scan_run_id": runID, "error": err.Error()})
 					c.JSON(http.StatusExpectationFailed, response)
 					return
 				}
-				response = append(response, gin.H{"network": network, "message": "ok"})
+				response = append(response, gin.H{"network": network, "scan_run_id": runID, "message": "ok"})
 				log.WithFields(log.Fields{"queue": viper.GetString("collector.worker.queue"), "subject": subject, "payload": s}).Info("sent")
 			}
 		} else {